@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ftl/tetra-cli/pkg/radio"
+	"github.com/ftl/tetra-pei/serial"
+	"github.com/spf13/cobra"
+
+	"github.com/ftl/tetra-mess/pkg/connection"
+	"github.com/ftl/tetra-mess/pkg/data"
+	"github.com/ftl/tetra-mess/pkg/demo"
+	"github.com/ftl/tetra-mess/pkg/scanner"
+	"github.com/ftl/tetra-mess/pkg/session"
+)
+
+// runWithDevices runs the given function with all devices given by --devices. Without --devices, the single device
+// given by --device is used and its data points are not tagged with a device name.
+func runWithDevices(run func(context.Context, []scanner.Device, *cobra.Command, []string)) func(*cobra.Command, []string) {
+	runWithSingleDevice := runWithPEI(func(ctx context.Context, pei radio.PEI, cmd *cobra.Command, args []string) {
+		run(ctx, []scanner.Device{{PEI: pei}}, cmd, args)
+	})
+	return func(cmd *cobra.Command, args []string) {
+		if len(rootFlags.devices) == 0 {
+			runWithSingleDevice(cmd, args)
+			return
+		}
+
+		ctx, cancel := context.WithCancel(cmd.Context())
+		defer cancel()
+
+		devices := make([]scanner.Device, 0, len(rootFlags.devices))
+		names := make(map[string]bool)
+		for _, spec := range rootFlags.devices {
+			name, portName := parseDeviceSpec(spec)
+			if names[name] {
+				fatalf("duplicate device name: %s", name)
+			}
+			if strings.ContainsAny(name, `,"'{}`) {
+				fatalf("invalid device name: %s", name)
+			}
+			names[name] = true
+
+			pei, err := openDevice(ctx, portName)
+			if err != nil {
+				fatalf("cannot open device %s: %v", name, err)
+			}
+			defer pei.Close()
+
+			if rootFlags.recordFilename != "" {
+				filename := filenameForDevice(rootFlags.recordFilename, name)
+				file, err := os.Create(filename)
+				if err != nil {
+					fatalf("cannot create session file %s: %v", filename, err)
+				}
+				defer file.Close()
+				pei = session.NewRecorder(pei, file)
+			}
+
+			devices = append(devices, scanner.Device{Name: name, PEI: pei})
+		}
+
+		run(ctx, devices, cmd, args)
+	}
+}
+
+// parseDeviceSpec parses a device given as [<name>=]<device>. Without name, the base name of the device is used.
+func parseDeviceSpec(spec string) (string, string) {
+	name, portName, hasName := strings.Cut(spec, "=")
+	if hasName {
+		return name, portName
+	}
+	return filepath.Base(strings.TrimPrefix(spec, replayDevicePrefix)), spec
+}
+
+func openDevice(ctx context.Context, portName string) (radio.PEI, error) {
+	switch {
+	case strings.ToLower(portName) == "demo":
+		return demo.NewDemo(), nil
+	case strings.HasPrefix(strings.ToLower(portName), replayDevicePrefix):
+		return openReplay(portName[len(replayDevicePrefix):])
+	case rootFlags.reconnect:
+		return connection.Open(ctx, openSerialPEI(portName), initializeRadio, logErrorf)
+	default:
+		return serial.Open(portName)
+	}
+}
+
+// filenameForDevice inserts the device name before the extension of the given filename.
+func filenameForDevice(filename string, device string) string {
+	if device == "" {
+		return filename
+	}
+	base, ext := data.SplitFilename(filename)
+	return fmt.Sprintf("%s-%s%s", base, device, ext)
+}
@@ -1,15 +1,20 @@
 package cmd
 
 import (
+	"errors"
+	"fmt"
 	"io"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 
 	"github.com/ftl/tetra-mess/pkg/data"
+	"github.com/ftl/tetra-mess/pkg/geojson"
 	"github.com/ftl/tetra-mess/pkg/gpx"
+	"github.com/ftl/tetra-mess/pkg/handover"
 	"github.com/ftl/tetra-mess/pkg/kml"
 	"github.com/ftl/tetra-mess/pkg/quality"
 )
@@ -17,14 +22,38 @@ import (
 var evalFlags = struct {
 	name           string
 	outputFilename string
+	maxHDOP        float64
+	minFixType     string
+	minSpeed       float64
+	maxSpeed       float64
+	clean          string
+	cleanMaxSpeed  float64
+	cleanMaxGap    time.Duration
+	where          string
+	colors         string
+	legendImage    bool
 }{}
 
 var evalTrackFlags = struct {
 	lac          string
 	carrier      string
+	serving      bool
 	outputFormat string
 }{}
 
+var evalQualityFlags = struct {
+	outputFormat string
+}{}
+
+var evalHandoversFlags = struct {
+	outputFormat string
+}{}
+
+var evalTableFlags = struct {
+	delimiter        string
+	decimalSeparator string
+}{}
+
 var evalCmd = &cobra.Command{
 	Use:   "eval",
 	Short: "Evaluate a signal trace file",
@@ -32,9 +61,10 @@ var evalCmd = &cobra.Command{
 
 var evalTrackCmd = &cobra.Command{
 	Use:   "track [tracefile][ tracefile...]",
-	Short: "Convert a signal trace file to a track file in the GPX or KML format",
-	Long: `Convert a signal trace file to a track file in the GPX or KML format.
+	Short: "Convert a signal trace file to a track file in the GPX, KML, KMZ, or GeoJSON format",
+	Long: `Convert a signal trace file to a track file in the GPX, KML, KMZ, or GeoJSON format.
 If no LAC or carrier is given, the best server will be used for each GPS position.
+With --serving, the cell the radio was actually registered to will be used instead of the best server.
 If no output filename is given, the filename is derived from the trace filename(s).
 `,
 	Run: runEvalTrack,
@@ -43,23 +73,75 @@ If no output filename is given, the filename is derived from the trace filename(
 var evalQualityCmd = &cobra.Command{
 	Use:   "quality [tracefile][ tracefile...]",
 	Short: "Evaluate the measurements from one or more signal trace files to visualize the coverage and signal quality of 100x100m fields",
-	Run:   runEvalQuality,
+	Long: `Evaluate the measurements from one or more signal trace files to visualize the coverage and signal quality of 100x100m fields.
+The result can be written as KML or GeoJSON (fields as polygons), GPX (field centers as waypoints), CSV (one line per field), or JSON.
+`,
+	Run: runEvalQuality,
+}
+
+var evalHandoversCmd = &cobra.Command{
+	Use:   "handovers [eventfile][ eventfile...]",
+	Short: "Convert a handover event file to points in the GPX or KML format",
+	Long: `Convert a handover event file, as written by trace --handovers, to points in the GPX or KML format.
+If no output filename is given, the filename is derived from the event filename(s).
+`,
+	Run: runEvalHandovers,
+}
+
+var evalTableCmd = &cobra.Command{
+	Use:   "table [tracefile][ tracefile...]",
+	Short: "Convert a signal trace file to a table with one row per measurement, e.g. for spreadsheets",
+	Long: `Convert a signal trace file to a CSV table with one row per measurement.
+Each row contains the local time, the position, LAC, carrier, RSSI, and Cx of the three best servers, the SLD, and the number of usable servers.
+For spreadsheets with German locale, use --delimiter ";" --decimal-separator ",".
+If no output filename is given, the filename is derived from the trace filename(s).
+`,
+	Run: runEvalTable,
 }
 
 func init() {
 	evalCmd.PersistentFlags().StringVar(&evalFlags.outputFilename, "output", "", "output filename")
 	evalCmd.PersistentFlags().StringVar(&evalFlags.name, "name", "", "a name for the evaluation result (default: derived from the input filename)")
+	evalCmd.PersistentFlags().Float64Var(&evalFlags.maxHDOP, "max-hdop", 0, "ignore data points with a higher HDOP (0: no limit)")
+	evalCmd.PersistentFlags().StringVar(&evalFlags.minFixType, "min-fix", "", "ignore data points with a lower GPS fix type (2d, 3d)")
+	evalCmd.PersistentFlags().Float64Var(&evalFlags.minSpeed, "min-speed", 0, "ignore data points with a lower speed in km/h")
+	evalCmd.PersistentFlags().Float64Var(&evalFlags.maxSpeed, "max-speed", 0, "ignore data points with a higher speed in km/h (0: no limit)")
+	evalCmd.PersistentFlags().StringVar(&evalFlags.where, "where", "", `only use data points that match the given filter expression, e.g. "lac = 1234 and rssi > -95 and time between 08:00 and 10:00"`)
+	evalCmd.PersistentFlags().StringVar(&evalFlags.colors, "colors", data.DefaultColorScheme.Name, fmt.Sprintf("color scheme of the map output (%s)", strings.Join(colorSchemeNames(), ", ")))
+	evalCmd.PersistentFlags().BoolVar(&evalFlags.legendImage, "legend-image", true, "with KML output, write the legend as PNG image next to the output file and show it on the map")
+	evalCmd.PersistentFlags().StringVar(&evalFlags.clean, "clean", "", fmt.Sprintf("clean implausible GPS positions (%s), eval handovers supports only drop", strings.Join(cleanModeNames(), ", ")))
+	evalCmd.PersistentFlags().Lookup("clean").NoOptDefVal = string(data.CleanDrop)
+	evalCmd.PersistentFlags().Float64Var(&evalFlags.cleanMaxSpeed, "clean-max-speed", data.DefaultCleanMaxSpeed*3.6, "with --clean, positions that require a higher speed in km/h are implausible")
+	evalCmd.PersistentFlags().DurationVar(&evalFlags.cleanMaxGap, "clean-max-gap", data.DefaultCleanMaxGap, "with --clean, positions repeated for a longer time are stale, and no positions are interpolated over a longer time")
 
 	evalTrackCmd.Flags().StringVar(&evalTrackFlags.lac, "lac", "", "LAC of a specific base station to filter for (can be given as decimal or hexadecimal value)")
 	evalTrackCmd.Flags().StringVar(&evalTrackFlags.carrier, "carrier", "", "carrier of a specific base station to filter for (can be given as decimal or hexadecimal value)")
-	evalTrackCmd.Flags().StringVar(&evalTrackFlags.outputFormat, "format", "kml", "output format (gpx, kml)")
+	evalTrackCmd.Flags().BoolVar(&evalTrackFlags.serving, "serving", false, "use the actual serving cell for each GPS position instead of the best server")
+	evalTrackCmd.Flags().StringVar(&evalTrackFlags.outputFormat, "format", "kml", "output format (gpx, kml, kmz, geojson)")
+
+	addGeofenceFlags(evalTrackCmd)
+
+	evalQualityCmd.Flags().StringVar(&evalQualityFlags.outputFormat, "format", "kml", fmt.Sprintf("output format (%s)", strings.Join(qualityFormatNames(), ", ")))
+
+	addGeofenceFlags(evalQualityCmd)
+
+	evalHandoversCmd.Flags().StringVar(&evalHandoversFlags.outputFormat, "format", "kml", "output format (gpx, kml)")
+
+	evalTableCmd.Flags().StringVar(&evalTableFlags.delimiter, "delimiter", ",", `column delimiter, use "tab" for tab separated values`)
+	evalTableCmd.Flags().StringVar(&evalTableFlags.decimalSeparator, "decimal-separator", ".", "decimal separator (. or ,)")
+
+	addGeofenceFlags(evalTableCmd)
 
 	evalCmd.AddCommand(evalTrackCmd)
 	evalCmd.AddCommand(evalQualityCmd)
+	evalCmd.AddCommand(evalHandoversCmd)
+	evalCmd.AddCommand(evalTableCmd)
 	rootCmd.AddCommand(evalCmd)
 }
 
-type trackWriter func(out io.Writer, trackname string, dataPoints []data.DataPoint) error
+// trackWriter writes the complete track at once. The track formats cannot be written point by point: KML, GPX and
+// GeoJSON are built as one document, and KMZ groups the data points by LAC and GAN level.
+type trackWriter func(out io.Writer, trackname string, legend data.Legend, dataPoints []data.DataPoint) error
 
 func runEvalTrack(cmd *cobra.Command, args []string) {
 	if len(args) == 0 {
@@ -81,6 +163,8 @@ func runEvalTrack(cmd *cobra.Command, args []string) {
 		if err == nil {
 			filter = data.FilterByCarrier(value)
 		}
+	case evalTrackFlags.serving:
+		filter = data.FilterServingCell()
 	default:
 		filter = data.FilterBestServer()
 	}
@@ -88,6 +172,37 @@ func runEvalTrack(cmd *cobra.Command, args []string) {
 		cmd.PrintErrf("Error parsing filter value: %v\n", err)
 		return
 	}
+	gpsFilter, err := gpsQualityFilter()
+	if err != nil {
+		cmd.PrintErrf("Error parsing GPS filter: %v\n", err)
+		return
+	}
+	profile, err := ganProfile()
+	if err != nil {
+		cmd.PrintErrf("Error: %v\n", err)
+		return
+	}
+	where, err := whereFilter(profile)
+	if err != nil {
+		cmd.PrintErrf("Error: %v\n", err)
+		return
+	}
+	geofence, err := geofencePredicate()
+	if err != nil {
+		cmd.PrintErrf("Error: %v\n", err)
+		return
+	}
+	filter = data.Filters(gpsFilter, where, geofence, filter)
+	newCleaner, err := cleaningFilter()
+	if err != nil {
+		cmd.PrintErrf("Error: %v\n", err)
+		return
+	}
+	legend, err := mapLegend(profile)
+	if err != nil {
+		cmd.PrintErrf("Error: %v\n", err)
+		return
+	}
 
 	var writeTrack trackWriter
 	switch strings.ToLower(evalTrackFlags.outputFormat) {
@@ -95,6 +210,10 @@ func runEvalTrack(cmd *cobra.Command, args []string) {
 		writeTrack = gpx.WriteDataPointsAsGPX
 	case "kml":
 		writeTrack = kml.WriteDataPointsAsKML
+	case "kmz":
+		writeTrack = kml.WriteDataPointsAsKMZ
+	case "geojson":
+		writeTrack = geojson.WriteDataPointsAsGeoJSON
 	default:
 		cmd.PrintErrf("Unsupported output format: %s\n", evalTrackFlags.outputFormat)
 		return
@@ -108,10 +227,16 @@ func runEvalTrack(cmd *cobra.Command, args []string) {
 
 		trackname := evalFlags.name
 		if trackname == "" {
-			trackname = filepath.Base(inputFilename)
+			trackname = evaluationName(inputFilename)
 		}
 
-		err := processTrackInputFile(inputFilename, outputFilename, trackname, filter, writeTrack)
+		outputLegend, err := legendForOutput(legend, outputFilename, evalTrackFlags.outputFormat)
+		if err != nil {
+			cmd.PrintErrf("Error: %v\n", err)
+			continue
+		}
+
+		err = processTrackInputFile(cmd, inputFilename, outputFilename, trackname, outputLegend, data.Filters(newCleaner(), filter), writeTrack)
 		if err != nil {
 			cmd.PrintErrf("Error processing input file %s into %s: %v\n", inputFilename, outputFilename, err)
 			continue
@@ -119,21 +244,24 @@ func runEvalTrack(cmd *cobra.Command, args []string) {
 	}
 }
 
-func processTrackInputFile(inputFilename, outputFilename string, trackname string, filter data.Filter, writeTrack trackWriter) error {
-	outputFile, err := os.Create(outputFilename)
+// processTrackInputFile streams the input file through the filter, but needs to keep all data points that pass the
+// filter in memory, because the trackWriter needs the whole track.
+func processTrackInputFile(cmd *cobra.Command, inputFilename, outputFilename string, trackname string, legend data.Legend, filter data.Filter, writeTrack trackWriter) error {
+	var dataPoints []data.DataPoint
+	err := forEachFilteredMeasurement(cmd, inputFilename, filter, func(measurement []data.DataPoint) {
+		dataPoints = append(dataPoints, measurement...)
+	})
 	if err != nil {
 		return err
 	}
-	defer outputFile.Close()
 
-	dataPoints, err := readInputFile(inputFilename)
+	outputFile, err := os.Create(outputFilename)
 	if err != nil {
 		return err
 	}
+	defer outputFile.Close()
 
-	dataPoints = filter.Filter(dataPoints)
-
-	return writeTrack(outputFile, trackname, dataPoints)
+	return writeTrack(outputFile, trackname, legend, dataPoints)
 }
 
 func runEvalQuality(cmd *cobra.Command, args []string) {
@@ -143,23 +271,81 @@ func runEvalQuality(cmd *cobra.Command, args []string) {
 	}
 	name := evalFlags.name
 	outputFilename := evalFlags.outputFilename
+	filter, err := gpsQualityFilter()
+	if err != nil {
+		cmd.PrintErrf("Error parsing GPS filter: %v\n", err)
+		return
+	}
+	profile, err := ganProfile()
+	if err != nil {
+		cmd.PrintErrf("Error: %v\n", err)
+		return
+	}
+	where, err := whereFilter(profile)
+	if err != nil {
+		cmd.PrintErrf("Error: %v\n", err)
+		return
+	}
+	geofence, err := geofencePredicate()
+	if err != nil {
+		cmd.PrintErrf("Error: %v\n", err)
+		return
+	}
+	filter = data.Filters(filter, where, geofence)
+	newCleaner, err := cleaningFilter()
+	if err != nil {
+		cmd.PrintErrf("Error: %v\n", err)
+		return
+	}
+	legend, err := mapLegend(profile)
+	if err != nil {
+		cmd.PrintErrf("Error: %v\n", err)
+		return
+	}
 
-	qualityReport := quality.NewQualityReport()
+	format, err := lookupQualityFormat(evalQualityFlags.outputFormat)
+	if err != nil {
+		cmd.PrintErrf("Error: %v\n", err)
+		return
+	}
+	if outputFilename != "" {
+		err := format.validateOutputFilename(outputFilename)
+		if err != nil {
+			cmd.PrintErrf("Error: %v\n", err)
+			return
+		}
+	}
+
+	qualityReport := quality.NewQualityReport(profile)
+	var input qualityInput
 	for _, inputFilename := range args {
-		if outputFilename == "" && evalFlags.outputFilename == "" {
-			outputFilename = outputFilenameFor(inputFilename, evalTrackFlags.outputFormat)
+		if outputFilename == "" {
+			outputFilename = outputFilenameFor(inputFilename, format.defaultExtension())
+			if outputFilename == inputFilename {
+				outputFilename = filenameForDevice(outputFilename, "quality")
+			}
 		}
 		if name == "" {
-			name = filepath.Base(inputFilename)
+			name = evaluationName(inputFilename)
 		}
 
-		err := processQualityInputFile(inputFilename, qualityReport)
+		err := processQualityInputFile(cmd, inputFilename, data.Filters(newCleaner(), filter), qualityReport, &input)
 		if err != nil {
 			cmd.PrintErrf("Error processing input file %s: %v\n", inputFilename, err)
 			continue
 		}
 	}
-	fieldReports := qualityReport.FieldReports()
+	err = format.validateInput(input)
+	if err != nil {
+		cmd.PrintErrf("Cannot write %s output: %v\n", format.name, err)
+		return
+	}
+
+	legend, err = legendForOutput(legend, outputFilename, format.name)
+	if err != nil {
+		cmd.PrintErrf("Error: %v\n", err)
+		return
+	}
 
 	outputFile, err := os.Create(outputFilename)
 	if err != nil {
@@ -167,38 +353,388 @@ func runEvalQuality(cmd *cobra.Command, args []string) {
 		return
 	}
 	defer outputFile.Close()
-	kml.WriteFieldReportsAsKML(outputFile, name, fieldReports)
+	err = format.write(outputFile, name, legend, qualityReport.FieldReports())
+	if err != nil {
+		cmd.PrintErrf("Error writing output file %s: %v\n", outputFilename, err)
+	}
+}
+
+func processQualityInputFile(cmd *cobra.Command, inputFilename string, filter data.Filter, qualityReport *quality.QualityReport, input *qualityInput) error {
+	return forEachFilteredMeasurement(cmd, inputFilename, filter, func(measurement []data.DataPoint) {
+		for _, dataPoint := range measurement {
+			qualityReport.Add(dataPoint)
+			input.Add(dataPoint)
+		}
+	})
 }
 
-func processQualityInputFile(inputFilename string, qualityReport *quality.QualityReport) error {
-	dataPoints, err := readInputFile(inputFilename)
+type handoverWriter func(out io.Writer, name string, legend data.Legend, events []handover.Event) error
+
+func runEvalHandovers(cmd *cobra.Command, args []string) {
+	if len(args) == 0 {
+		cmd.Help()
+		return
+	}
+
+	var writeHandovers handoverWriter
+	switch strings.ToLower(evalHandoversFlags.outputFormat) {
+	case "gpx":
+		writeHandovers = gpx.WriteHandoversAsGPX
+	case "kml":
+		writeHandovers = kml.WriteHandoversAsKML
+	default:
+		cmd.PrintErrf("Unsupported output format: %s\n", evalHandoversFlags.outputFormat)
+		return
+	}
+	err := checkHandoverCleanMode()
+	if err != nil {
+		cmd.PrintErrf("Error: %v\n", err)
+		return
+	}
+	profile, err := ganProfile()
+	if err != nil {
+		cmd.PrintErrf("Error: %v\n", err)
+		return
+	}
+	where, err := whereFilter(profile)
+	if err != nil {
+		cmd.PrintErrf("Error: %v\n", err)
+		return
+	}
+	legend, err := mapLegend(profile)
+	if err != nil {
+		cmd.PrintErrf("Error: %v\n", err)
+		return
+	}
+
+	for _, inputFilename := range args {
+		outputFilename := evalFlags.outputFilename
+		if evalFlags.outputFilename == "" {
+			outputFilename = outputFilenameFor(inputFilename, evalHandoversFlags.outputFormat)
+		}
+
+		name := evalFlags.name
+		if name == "" {
+			name = filepath.Base(inputFilename)
+		}
+
+		outputLegend, err := legendForOutput(legend, outputFilename, evalHandoversFlags.outputFormat)
+		if err != nil {
+			cmd.PrintErrf("Error: %v\n", err)
+			continue
+		}
+
+		err = processHandoverInputFile(inputFilename, outputFilename, name, outputLegend, where, writeHandovers)
+		if err != nil {
+			cmd.PrintErrf("Error processing input file %s into %s: %v\n", inputFilename, outputFilename, err)
+			continue
+		}
+	}
+}
+
+func processHandoverInputFile(inputFilename, outputFilename string, name string, legend data.Legend, where data.Predicate, writeHandovers handoverWriter) error {
+	inputFile, err := os.Open(inputFilename)
+	if err != nil {
+		return err
+	}
+	defer inputFile.Close()
+
+	events, err := handover.ReadEvents(inputFile)
+	if err != nil {
+		return err
+	}
+	if evalFlags.clean != "" {
+		events = dropImplausibleEvents(events)
+	}
+	events = filterEvents(events, where)
+
+	outputFile, err := os.Create(outputFilename)
+	if err != nil {
+		return err
+	}
+	defer outputFile.Close()
+
+	return writeHandovers(outputFile, name, legend, events)
+}
+
+func runEvalTable(cmd *cobra.Command, args []string) {
+	if len(args) == 0 {
+		cmd.Help()
+		return
+	}
+
+	format, err := tableFormat()
+	if err != nil {
+		cmd.PrintErrf("Error: %v\n", err)
+		return
+	}
+	filter, err := gpsQualityFilter()
+	if err != nil {
+		cmd.PrintErrf("Error parsing GPS filter: %v\n", err)
+		return
+	}
+	profile, err := ganProfile()
+	if err != nil {
+		cmd.PrintErrf("Error: %v\n", err)
+		return
+	}
+	where, err := whereFilter(profile)
+	if err != nil {
+		cmd.PrintErrf("Error: %v\n", err)
+		return
+	}
+	geofence, err := geofencePredicate()
+	if err != nil {
+		cmd.PrintErrf("Error: %v\n", err)
+		return
+	}
+	filter = data.Filters(filter, where, geofence)
+	newCleaner, err := cleaningFilter()
+	if err != nil {
+		cmd.PrintErrf("Error: %v\n", err)
+		return
+	}
+
+	for _, inputFilename := range args {
+		outputFilename := evalFlags.outputFilename
+		if evalFlags.outputFilename == "" {
+			outputFilename = filenameForDevice(outputFilenameFor(inputFilename, "csv"), "table")
+		}
+
+		err := processTableInputFile(cmd, inputFilename, outputFilename, format, profile, data.Filters(newCleaner(), filter))
+		if err != nil {
+			cmd.PrintErrf("Error processing input file %s into %s: %v\n", inputFilename, outputFilename, err)
+			continue
+		}
+	}
+}
+
+func processTableInputFile(cmd *cobra.Command, inputFilename, outputFilename string, format quality.TableFormat, profile data.GANProfile, filter data.Filter) error {
+	outputFile, err := os.Create(outputFilename)
+	if err != nil {
+		return err
+	}
+	defer outputFile.Close()
+
+	table := quality.NewTableWriter(outputFile, format, profile)
+	err = table.WriteHeader()
+	if err != nil {
+		return err
+	}
+
+	var writeErr error
+	err = forEachFilteredMeasurement(cmd, inputFilename, filter, func(dataPoints []data.DataPoint) {
+		if writeErr != nil {
+			return
+		}
+		// the cleaner may hold back data points and release several measurements at once
+		var measurement quality.Measurement
+		for _, dataPoint := range dataPoints {
+			if len(measurement.DataPoints) > 0 && dataPoint.MeasurementID() != measurement.ID {
+				writeErr = table.Write(measurement)
+				measurement = quality.Measurement{}
+			}
+			measurement.Add(dataPoint)
+		}
+		if writeErr == nil {
+			writeErr = table.Write(measurement)
+		}
+	})
 	if err != nil {
 		return err
 	}
+	if writeErr != nil {
+		return writeErr
+	}
+
+	return table.Flush()
+}
 
-	for _, dataPoint := range dataPoints {
-		qualityReport.Add(dataPoint)
+// tableFormat parses --delimiter and --decimal-separator.
+func tableFormat() (quality.TableFormat, error) {
+	delimiter := evalTableFlags.delimiter
+	if strings.EqualFold(delimiter, "tab") {
+		delimiter = "\t"
+	}
+	if len([]rune(delimiter)) != 1 {
+		return quality.TableFormat{}, fmt.Errorf("the delimiter must be a single character")
+	}
+	if len([]rune(evalTableFlags.decimalSeparator)) != 1 {
+		return quality.TableFormat{}, fmt.Errorf("the decimal separator must be a single character")
+	}
+	result := quality.TableFormat{
+		Delimiter:        []rune(delimiter)[0],
+		DecimalSeparator: []rune(evalTableFlags.decimalSeparator)[0],
+	}
+	return result, result.Validate()
+}
+
+// gpsQualityFilter creates the filter for the GPS quality given by --max-hdop, --min-fix, --min-speed, and --max-speed.
+func gpsQualityFilter() (data.Filter, error) {
+	var filters []data.Filter
+	if evalFlags.maxHDOP > 0 {
+		filters = append(filters, data.FilterByMaxHDOP(evalFlags.maxHDOP))
+	}
+	if evalFlags.minFixType != "" {
+		minFixType, err := data.ParseFixType(evalFlags.minFixType)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, data.FilterByMinFixType(minFixType))
+	}
+	if evalFlags.minSpeed > 0 || evalFlags.maxSpeed > 0 {
+		filters = append(filters, data.FilterBySpeed(evalFlags.minSpeed/3.6, evalFlags.maxSpeed/3.6))
+	}
+	return data.Filters(filters...), nil
+}
+
+// cleaningFilter parses --clean and returns a function that creates the filter for implausible GPS positions. The
+// filter keeps state, hence each input file needs a new one.
+func cleaningFilter() (func() data.Filter, error) {
+	if evalFlags.clean == "" {
+		return func() data.Filter { return data.Filters() }, nil
+	}
+	mode, err := data.ParseCleanMode(evalFlags.clean)
+	if err != nil {
+		return nil, err
+	}
+	return func() data.Filter {
+		return data.NewCleaner(mode, evalFlags.cleanMaxSpeed/3.6, evalFlags.cleanMaxGap)
+	}, nil
+}
+
+func cleanModeNames() []string {
+	result := make([]string, 0, len(data.CleanModes))
+	for _, mode := range data.CleanModes {
+		result = append(result, string(mode))
+	}
+	return result
+}
+
+// checkHandoverCleanMode rejects the clean modes that cannot be applied to handover events: the events cannot be
+// flagged, and they are too far apart to interpolate their positions, so implausible positions can only be dropped.
+func checkHandoverCleanMode() error {
+	if evalFlags.clean == "" {
+		return nil
+	}
+	mode, err := data.ParseCleanMode(evalFlags.clean)
+	if err != nil {
+		return err
+	}
+	if mode != data.CleanDrop {
+		return fmt.Errorf("eval handovers can only drop implausible positions, use --clean=%s", data.CleanDrop)
 	}
 	return nil
 }
 
+// dropImplausibleEvents drops all handover events with an implausible position.
+func dropImplausibleEvents(events []handover.Event) []handover.Event {
+	cleaner := data.NewCleaner(data.CleanDrop, evalFlags.cleanMaxSpeed/3.6, evalFlags.cleanMaxGap)
+	result := make([]handover.Event, 0, len(events))
+	for _, event := range events {
+		position := data.Position{
+			Latitude:   event.Latitude,
+			Longitude:  event.Longitude,
+			Satellites: event.Satellites,
+			Timestamp:  event.Timestamp,
+		}
+		if cleaner.Check(position) == data.NoGPSIssue {
+			result = append(result, event)
+		}
+	}
+	return result
+}
+
+// whereFilter parses the filter expression given by --where. Without expression, all data points are kept.
+func whereFilter(profile data.GANProfile) (data.Predicate, error) {
+	if evalFlags.where == "" {
+		return func(data.DataPoint) bool { return true }, nil
+	}
+	return data.ParseFilterExpression(evalFlags.where, profile)
+}
+
+// filterEvents keeps the handover events that match the given predicate. The predicate sees the new cell of the
+// event as LAC and carrier.
+func filterEvents(events []handover.Event, where data.Predicate) []handover.Event {
+	result := make([]handover.Event, 0, len(events))
+	for _, event := range events {
+		dataPoint := data.DataPoint{
+			Latitude:   event.Latitude,
+			Longitude:  event.Longitude,
+			Satellites: event.Satellites,
+			Timestamp:  event.Timestamp,
+			LAC:        event.NewLAC,
+			Carrier:    event.NewCarrier,
+			RSSI:       event.RSSI,
+			Serving:    event.Kind == handover.Handover,
+			Device:     event.Device,
+		}
+		if where(dataPoint) {
+			result = append(result, event)
+		}
+	}
+	return result
+}
+
+// evaluationName derives the name of an evaluation result from the filename and the metadata of the trace file.
+func evaluationName(inputFilename string) string {
+	result := filepath.Base(inputFilename)
+	metadata, err := readMetadata(inputFilename)
+	if err != nil {
+		return result
+	}
+	title := metadata.Title()
+	if title == "" {
+		return result
+	}
+	return fmt.Sprintf("%s (%s)", result, title)
+}
+
 func outputFilenameFor(inputFilename string, formatExtension string) string {
 	if inputFilename == "" {
 		panic("input filename cannot be empty")
 	}
 
-	dir := filepath.Dir(inputFilename)
-	base := filepath.Base(inputFilename)
-	filename := base[:len(base)-len(filepath.Ext(base))]
-	return filepath.Join(dir, filename+"."+formatExtension)
+	base, _ := data.SplitFilename(inputFilename)
+	return base + "." + formatExtension
 }
 
-func readInputFile(inputFilename string) ([]data.DataPoint, error) {
+// forEachFilteredMeasurement calls f with the filtered data points of each measurement. At the end of the input file,
+// f is called once more with the data points that the filter held back.
+func forEachFilteredMeasurement(cmd *cobra.Command, inputFilename string, filter data.Filter, f func([]data.DataPoint)) error {
+	err := forEachMeasurement(cmd, inputFilename, func(measurement []data.DataPoint) {
+		f(filter.Filter(measurement))
+	})
+	if err != nil {
+		return err
+	}
+
+	remaining := data.Flush(filter)
+	if len(remaining) > 0 {
+		f(remaining)
+	}
+	return nil
+}
+
+// forEachMeasurement streams the data points of the given trace file and calls f for every measurement. Lines
+// that cannot be parsed are reported and skipped.
+func forEachMeasurement(cmd *cobra.Command, inputFilename string, f func([]data.DataPoint)) error {
 	file, err := os.Open(inputFilename)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer file.Close()
 
-	return data.ReadDataPoints(file)
+	for measurement, err := range data.Measurements(data.DataPoints(file)) {
+		var lineErr *data.LineError
+		if errors.As(err, &lineErr) {
+			cmd.PrintErrf("%s:%d: %v\n", inputFilename, lineErr.Line, lineErr.Err)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		f(measurement)
+	}
+	return nil
 }
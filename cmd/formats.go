@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/ftl/tetra-mess/pkg/data"
+	"github.com/ftl/tetra-mess/pkg/geojson"
+	"github.com/ftl/tetra-mess/pkg/gpx"
+	"github.com/ftl/tetra-mess/pkg/kml"
+	"github.com/ftl/tetra-mess/pkg/quality"
+)
+
+// qualityFormat is an output format of eval quality.
+type qualityFormat struct {
+	name string
+	// extensions are the accepted extensions of the output file, the first one is used for derived filenames
+	extensions []string
+	write      func(out io.Writer, name string, legend data.Legend, fieldReports []quality.FieldReport) error
+	// validate checks if the evaluated data can be written in this format, optional
+	validate func(input qualityInput) error
+}
+
+var qualityFormats = map[string]qualityFormat{
+	"kml": {
+		extensions: []string{"kml"},
+		write:      kml.WriteFieldReportsAsKML,
+		validate:   requireValidPositions,
+	},
+	"gpx": {
+		extensions: []string{"gpx"},
+		write:      gpx.WriteFieldReportsAsGPX,
+		validate:   requireValidPositions,
+	},
+	"geojson": {
+		extensions: []string{"geojson", "json"},
+		write:      geojson.WriteFieldReportsAsGeoJSON,
+		validate:   requireValidPositions,
+	},
+	"csv": {
+		extensions: []string{"csv"},
+		write:      quality.WriteFieldReportsAsCSV,
+	},
+	"json": {
+		extensions: []string{"json"},
+		write:      quality.WriteFieldReportsAsJSON,
+	},
+}
+
+func qualityFormatNames() []string {
+	result := make([]string, 0, len(qualityFormats))
+	for name := range qualityFormats {
+		result = append(result, name)
+	}
+	slices.Sort(result)
+	return result
+}
+
+func lookupQualityFormat(name string) (qualityFormat, error) {
+	name = strings.ToLower(name)
+	result, ok := qualityFormats[name]
+	if !ok {
+		return qualityFormat{}, fmt.Errorf("unsupported output format %s, use one of %s", name, strings.Join(qualityFormatNames(), ", "))
+	}
+	result.name = name
+	return result, nil
+}
+
+func (f qualityFormat) defaultExtension() string {
+	return f.extensions[0]
+}
+
+// validateOutputFilename checks that the extension of the given output file fits to the format.
+func (f qualityFormat) validateOutputFilename(filename string) error {
+	ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(filename), "."))
+	if slices.Contains(f.extensions, ext) {
+		return nil
+	}
+	return fmt.Errorf("the output file %s does not fit to the %s format, use the extension .%s", filename, f.name, strings.Join(f.extensions, " or ."))
+}
+
+// qualityInput summarizes the data points that were evaluated for the quality report.
+type qualityInput struct {
+	dataPoints        int
+	dataPointsWithFix int
+}
+
+func (i *qualityInput) Add(dataPoint data.DataPoint) {
+	i.dataPoints++
+	if dataPoint.HasValidPosition() {
+		i.dataPointsWithFix++
+	}
+}
+
+func (f qualityFormat) validateInput(input qualityInput) error {
+	if f.validate == nil {
+		return nil
+	}
+	return f.validate(input)
+}
+
+func requireValidPositions(input qualityInput) error {
+	if input.dataPointsWithFix == 0 {
+		return fmt.Errorf("none of the %d data points has a valid GPS position, nothing to show on a map", input.dataPoints)
+	}
+	return nil
+}
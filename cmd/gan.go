@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/ftl/tetra-mess/pkg/data"
+)
+
+const ganProfilesFilename = "gan-profiles.json"
+
+// ganProfile returns the GAN profile selected with --gan-profile. User defined profiles are read from the file given
+// with --gan-profiles, or from the default file in the configuration directory, if it exists.
+func ganProfile() (data.GANProfile, error) {
+	filename := rootFlags.ganProfiles
+	required := filename != ""
+	if !required {
+		configDir, err := os.UserConfigDir()
+		if err == nil {
+			filename = filepath.Join(configDir, "tetra-mess", ganProfilesFilename)
+		}
+	}
+
+	var userProfiles []data.GANProfile
+	if filename != "" {
+		var err error
+		userProfiles, err = readGANProfiles(filename)
+		if errors.Is(err, fs.ErrNotExist) && !required {
+			userProfiles = nil
+		} else if err != nil {
+			return data.GANProfile{}, fmt.Errorf("cannot read GAN profiles from %s: %w", filename, err)
+		}
+	}
+
+	return data.FindGANProfile(rootFlags.ganProfile, userProfiles)
+}
+
+func readGANProfiles(filename string) ([]data.GANProfile, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return data.ReadGANProfiles(file)
+}
+
+func builtinGANProfileNames() []string {
+	result := make([]string, 0, len(data.BuiltinGANProfiles))
+	for _, profile := range data.BuiltinGANProfiles {
+		result = append(result, profile.Name)
+	}
+	return result
+}
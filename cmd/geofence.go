@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ftl/tetra-mess/pkg/data"
+	"github.com/ftl/tetra-mess/pkg/geojson"
+	"github.com/ftl/tetra-mess/pkg/kml"
+)
+
+var geofenceFlags = struct {
+	bbox          string
+	circle        string
+	areaFilenames []string
+}{}
+
+func addGeofenceFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&geofenceFlags.bbox, "bbox", "", "only use data points within the bounding box minLat,minLon,maxLat,maxLon")
+	cmd.Flags().StringVar(&geofenceFlags.circle, "circle", "", "only use data points within the circle lat,lon,radius (radius in metres)")
+	cmd.Flags().StringSliceVar(&geofenceFlags.areaFilenames, "area", nil, "only use data points within the polygons of the given GeoJSON or KML file (can be given multiple times)")
+}
+
+// geofencePredicate creates a predicate that keeps only data points inside the areas given by --bbox, --circle, and
+// --area. If several of them are given, a data point must be inside all of them. The polygons of all area files
+// form one area.
+func geofencePredicate() (data.Predicate, error) {
+	var predicates []data.Predicate
+	if geofenceFlags.bbox != "" {
+		bbox, err := data.ParseBoundingBox(geofenceFlags.bbox)
+		if err != nil {
+			return nil, err
+		}
+		predicates = append(predicates, data.InArea(bbox))
+	}
+	if geofenceFlags.circle != "" {
+		circle, err := data.ParseCircle(geofenceFlags.circle)
+		if err != nil {
+			return nil, err
+		}
+		predicates = append(predicates, data.InArea(circle))
+	}
+	if len(geofenceFlags.areaFilenames) > 0 {
+		var areas data.Areas
+		for _, filename := range geofenceFlags.areaFilenames {
+			fileAreas, err := readAreaFile(filename)
+			if err != nil {
+				return nil, fmt.Errorf("cannot read area file %s: %w", filename, err)
+			}
+			if len(fileAreas) == 0 {
+				return nil, fmt.Errorf("the area file %s contains no polygons", filename)
+			}
+			areas = append(areas, fileAreas...)
+		}
+		predicates = append(predicates, data.InArea(areas))
+	}
+	return data.And(predicates...), nil
+}
+
+func readAreaFile(filename string) (data.Areas, error) {
+	var readAreas func(io.Reader) (data.Areas, error)
+	switch data.FileFormat(filename) {
+	case "geojson", "json":
+		readAreas = geojson.ReadAreas
+	case "kml":
+		readAreas = kml.ReadAreas
+	default:
+		return nil, fmt.Errorf("unsupported area file format, use GeoJSON or KML")
+	}
+
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return readAreas(file)
+}
@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ftl/tetra-mess/pkg/data"
+	"github.com/ftl/tetra-mess/pkg/gpx"
+)
+
+var geotagFlags = struct {
+	outputFilename string
+	offset         time.Duration
+	maxGap         time.Duration
+}{}
+
+var geotagCmd = &cobra.Command{
+	Use:   "geotag [tracefile] [gpxfile][ gpxfile...]",
+	Short: "Add positions from GPX files to the data points of a signal trace file that have no GPS fix",
+	Long: `Add positions from GPX files to the data points of a signal trace file that have no GPS fix.
+The position of a data point is interpolated between the two GPX track points that enclose the timestamp of the data point.
+Use --offset if the clock of the GPS logger is not in sync with the clock of the trace: the offset is added to the timestamps of the trace.
+If no output filename is given, the filename is derived from the trace filename.
+`,
+	Run: runGeotag,
+}
+
+func init() {
+	geotagCmd.Flags().StringVar(&geotagFlags.outputFilename, "output", "", "output filename (default: <tracefile>-geotagged.<ext>)")
+	geotagCmd.Flags().DurationVar(&geotagFlags.offset, "offset", 0, "clock offset of the GPX files relative to the trace file, e.g. 2s or -1m30s")
+	geotagCmd.Flags().DurationVar(&geotagFlags.maxGap, "max-gap", time.Minute, "do not interpolate between GPX track points that are further apart in time")
+
+	rootCmd.AddCommand(geotagCmd)
+}
+
+func runGeotag(cmd *cobra.Command, args []string) {
+	if len(args) < 2 {
+		cmd.Help()
+		return
+	}
+	inputFilename := args[0]
+
+	outputFilename := geotagFlags.outputFilename
+	if outputFilename == "" {
+		outputFilename = filenameForDevice(inputFilename, "geotagged")
+	}
+	if outputFilename == inputFilename {
+		fatalf("the output file %s must not be the input file", outputFilename)
+	}
+	encoder, writeHeader, err := traceEncoder(outputFilename)
+	if err != nil {
+		fatal(err)
+	}
+
+	var track gpx.Track
+	for _, gpxFilename := range args[1:] {
+		gpxTrack, err := readGPXTrack(gpxFilename)
+		if err != nil {
+			fatalf("cannot read GPX file %s: %v", gpxFilename, err)
+		}
+		track = track.Join(gpxTrack)
+	}
+	if len(track) == 0 {
+		fatalf("the GPX files contain no track points with timestamps")
+	}
+
+	metadata, err := readMetadata(inputFilename)
+	if err != nil {
+		fatalf("cannot read metadata of input file %s: %v", inputFilename, err)
+	}
+
+	inputFile, err := os.Open(inputFilename)
+	if err != nil {
+		fatalf("cannot open input file %s: %v", inputFilename, err)
+	}
+	defer inputFile.Close()
+
+	outputFile, err := data.CreateFile(outputFilename)
+	if err != nil {
+		fatalf("cannot create output file %s: %v", outputFilename, err)
+	}
+	err = writeHeader(outputFile, metadata)
+	if err != nil {
+		fatalf("cannot write header to output file %s: %v", outputFilename, err)
+	}
+
+	var total, unpositioned, geotagged int
+	for dataPoint, err := range data.DataPoints(inputFile) {
+		var lineErr *data.LineError
+		if errors.As(err, &lineErr) {
+			cmd.PrintErrf("%s:%d: %v\n", inputFilename, lineErr.Line, lineErr.Err)
+			continue
+		}
+		if err != nil {
+			fatalf("cannot read input file %s: %v", inputFilename, err)
+		}
+
+		total++
+		if !dataPoint.Position().HasFix() {
+			unpositioned++
+		}
+		dataPoint, ok := track.Geotag(dataPoint, geotagFlags.offset, geotagFlags.maxGap)
+		if ok {
+			geotagged++
+		}
+		writeTraceDataPoints(outputFile, encoder, []data.DataPoint{dataPoint}, false)
+	}
+
+	err = outputFile.Close()
+	if err != nil {
+		fatalf("cannot close output file %s: %v", outputFilename, err)
+	}
+
+	cmd.Printf("%s: %d data points, %d without GPS fix, %d geotagged from %d GPX track points\n", outputFilename, total, unpositioned, geotagged, len(track))
+}
+
+func readGPXTrack(filename string) (gpx.Track, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return gpx.ReadTrack(file)
+}
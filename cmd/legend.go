@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ftl/tetra-mess/pkg/data"
+	"github.com/ftl/tetra-mess/pkg/kml"
+)
+
+// mapLegend returns the legend for the given GAN profile and the color scheme selected with --colors.
+func mapLegend(profile data.GANProfile) (data.Legend, error) {
+	colors, err := data.FindColorScheme(evalFlags.colors)
+	if err != nil {
+		return data.Legend{}, fmt.Errorf("%w, use one of %s", err, strings.Join(colorSchemeNames(), ", "))
+	}
+	return data.NewLegend(profile, colors), nil
+}
+
+// legendForOutput returns the legend to be used for the given output file. For KML output, the legend image is
+// written next to the output file, unless --legend-image=false.
+func legendForOutput(legend data.Legend, outputFilename string, format string) (data.Legend, error) {
+	if !strings.EqualFold(format, "kml") || !evalFlags.legendImage {
+		return legend, nil
+	}
+
+	imageFilename := legendImageFilename(outputFilename)
+	file, err := os.Create(imageFilename)
+	if err != nil {
+		return data.Legend{}, fmt.Errorf("cannot create legend image %s: %w", imageFilename, err)
+	}
+	defer file.Close()
+
+	err = kml.WriteLegendPNG(file, legend)
+	if err != nil {
+		return data.Legend{}, fmt.Errorf("cannot write legend image %s: %w", imageFilename, err)
+	}
+
+	legend.ImageHref = filepath.Base(imageFilename)
+	return legend, nil
+}
+
+// legendImageFilename derives the filename of the legend image from the output filename: track.kml -> track-legend.png
+func legendImageFilename(outputFilename string) string {
+	return strings.TrimSuffix(outputFilename, filepath.Ext(outputFilename)) + "-legend.png"
+}
+
+func colorSchemeNames() []string {
+	result := make([]string, 0, len(data.ColorSchemes))
+	for _, scheme := range data.ColorSchemes {
+		result = append(result, scheme.Name)
+	}
+	return result
+}
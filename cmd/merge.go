@@ -0,0 +1,163 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ftl/tetra-mess/pkg/data"
+)
+
+var mergeFlags = struct {
+	outputFilename string
+}{}
+
+var mergeCmd = &cobra.Command{
+	Use:   "merge [tracefile][ tracefile...]",
+	Short: "Merge several signal trace files into one",
+	Long: `Merge several signal trace files into one.
+The data points are sorted by their timestamp. Duplicate data points (same measurement, LAC, and carrier) are written only once.
+The metadata of all input files is combined.
+The output file can be in CSV or JSON format, depending on the file extension, optionally compressed with gzip (.gz) or zstd (.zst).
+`,
+	Run: runMerge,
+}
+
+func init() {
+	mergeCmd.Flags().StringVar(&mergeFlags.outputFilename, "output", "", "output filename")
+	mergeCmd.MarkFlagRequired("output")
+	addGeofenceFlags(mergeCmd)
+
+	rootCmd.AddCommand(mergeCmd)
+}
+
+type mergeInputSummary struct {
+	filename   string
+	dataPoints int
+	errors     int
+	duplicates int
+	outside    int
+}
+
+func runMerge(cmd *cobra.Command, args []string) {
+	if len(args) == 0 {
+		cmd.Help()
+		return
+	}
+
+	outputFilename := mergeFlags.outputFilename
+	encoder, writeHeader, err := traceEncoder(outputFilename)
+	if err != nil {
+		fatal(err)
+	}
+	if slices.Contains(args, outputFilename) {
+		fatalf("the output file %s must not be one of the input files", outputFilename)
+	}
+	inArea, err := geofencePredicate()
+	if err != nil {
+		fatal(err)
+	}
+
+	var dataPoints []data.DataPoint
+	var metadata data.Metadata
+	summaries := make([]mergeInputSummary, 0, len(args))
+	known := make(map[string]bool)
+	for _, inputFilename := range args {
+		summary := mergeInputSummary{filename: inputFilename}
+		inputMetadata, err := readMetadata(inputFilename)
+		if err != nil {
+			fatalf("cannot read metadata of input file %s: %v", inputFilename, err)
+		}
+		metadata = metadata.Merge(inputMetadata)
+
+		err = readMergeInputFile(cmd, inputFilename, func(dataPoint data.DataPoint) {
+			summary.dataPoints++
+			if !inArea(dataPoint) {
+				summary.outside++
+				return
+			}
+			key := fmt.Sprintf("%s-%d-%d", dataPoint.MeasurementID(), dataPoint.LAC, dataPoint.Carrier)
+			if known[key] {
+				summary.duplicates++
+				return
+			}
+			known[key] = true
+			dataPoints = append(dataPoints, dataPoint)
+		}, func() {
+			summary.errors++
+		})
+		if err != nil {
+			fatalf("cannot read input file %s: %v", inputFilename, err)
+		}
+		summaries = append(summaries, summary)
+	}
+
+	// the stable sort keeps the data points of the same measurement together
+	slices.SortStableFunc(dataPoints, func(a, b data.DataPoint) int {
+		return a.Timestamp.Compare(b.Timestamp)
+	})
+
+	outputFile, err := data.CreateFile(outputFilename)
+	if err != nil {
+		fatalf("cannot create output file %s: %v", outputFilename, err)
+	}
+	err = writeHeader(outputFile, metadata)
+	if err != nil {
+		fatalf("cannot write header to output file %s: %v", outputFilename, err)
+	}
+	writeTraceDataPoints(outputFile, encoder, dataPoints, false)
+	err = outputFile.Close()
+	if err != nil {
+		fatalf("cannot close output file %s: %v", outputFilename, err)
+	}
+
+	printMergeSummary(cmd, summaries, dataPoints, outputFilename)
+}
+
+func readMergeInputFile(cmd *cobra.Command, inputFilename string, add func(data.DataPoint), countError func()) error {
+	file, err := os.Open(inputFilename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	for dataPoint, err := range data.DataPoints(file) {
+		var lineErr *data.LineError
+		if errors.As(err, &lineErr) {
+			cmd.PrintErrf("%s:%d: %v\n", inputFilename, lineErr.Line, lineErr.Err)
+			countError()
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		add(dataPoint)
+	}
+	return nil
+}
+
+func printMergeSummary(cmd *cobra.Command, summaries []mergeInputSummary, dataPoints []data.DataPoint, outputFilename string) {
+	totalDataPoints := 0
+	totalDuplicates := 0
+	totalOutside := 0
+	totalErrors := 0
+	for _, summary := range summaries {
+		cmd.Printf("%s: %d data points, %d duplicates dropped, %d outside the area dropped, %d invalid lines\n", summary.filename, summary.dataPoints, summary.duplicates, summary.outside, summary.errors)
+		totalDataPoints += summary.dataPoints
+		totalDuplicates += summary.duplicates
+		totalOutside += summary.outside
+		totalErrors += summary.errors
+	}
+
+	cmd.Printf("merged %d files with %d data points into %s: %d data points written, %d duplicates dropped, %d outside the area dropped, %d invalid lines\n",
+		len(summaries), totalDataPoints, outputFilename, len(dataPoints), totalDuplicates, totalOutside, totalErrors)
+	if len(dataPoints) > 0 {
+		cmd.Printf("time range: %s to %s\n",
+			dataPoints[0].Timestamp.Format(time.RFC3339),
+			dataPoints[len(dataPoints)-1].Timestamp.Format(time.RFC3339))
+	}
+}
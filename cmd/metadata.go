@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"context"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ftl/tetra-mess/pkg/data"
+	"github.com/ftl/tetra-mess/pkg/scanner"
+)
+
+var metadataFlags = struct {
+	operator string
+	vehicle  string
+	antenna  string
+}{}
+
+func addMetadataFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&metadataFlags.operator, "operator", "", "name of the operator, recorded in the trace file")
+	cmd.Flags().StringVar(&metadataFlags.vehicle, "vehicle", "", "description of the vehicle, recorded in the trace file")
+	cmd.Flags().StringVar(&metadataFlags.antenna, "antenna", "", "description of the antenna, recorded in the trace file")
+}
+
+// traceMetadata collects the metadata for a trace file from the flags and the given radios. The radios must
+// already be initialized.
+func traceMetadata(ctx context.Context, devices []scanner.Device, log scanner.Logger) data.Metadata {
+	result := data.Metadata{
+		Version:  version,
+		Operator: metadataFlags.operator,
+		Vehicle:  metadataFlags.vehicle,
+		Antenna:  metadataFlags.antenna,
+	}
+	for _, device := range devices {
+		radioInfo, err := scanner.RequestRadioInfo(ctx, device.PEI)
+		if err != nil {
+			log("cannot read all radio information of %s: %v", deviceName(device), err)
+		}
+		radioInfo.Device = device.Name
+		result.Radios = append(result.Radios, radioInfo)
+	}
+	return result
+}
+
+func deviceName(device scanner.Device) string {
+	if device.Name == "" {
+		return "the radio"
+	}
+	return device.Name
+}
+
+// readMetadata reads the metadata from the header of the given trace file.
+func readMetadata(filename string) (data.Metadata, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return data.Metadata{}, err
+	}
+	defer file.Close()
+
+	return data.ReadMetadata(file)
+}
@@ -10,15 +10,36 @@ import (
 
 	"github.com/ftl/tetra-cli/pkg/cli"
 	"github.com/ftl/tetra-cli/pkg/radio"
+	"github.com/ftl/tetra-pei/serial"
 	"github.com/spf13/cobra"
 
+	"github.com/ftl/tetra-mess/pkg/connection"
+	"github.com/ftl/tetra-mess/pkg/data"
 	"github.com/ftl/tetra-mess/pkg/demo"
+	"github.com/ftl/tetra-mess/pkg/scanner"
+	"github.com/ftl/tetra-mess/pkg/session"
 )
 
-const defaultCommandTimeout = 5 * time.Second
+const (
+	defaultCommandTimeout  = 5 * time.Second
+	defaultScanDistance    = 100
+	defaultScanMaxInterval = 5 * time.Minute
+	replayDevicePrefix     = "replay:"
+)
 
 var version = "development"
 
+var rootFlags = struct {
+	devices        []string
+	radioType      string
+	gpsSource      string
+	reconnect      bool
+	recordFilename string
+	replaySpeed    float64
+	ganProfile     string
+	ganProfiles    string
+}{}
+
 var rootCmd = &cobra.Command{
 	Use:     "tetra-mess",
 	Version: version,
@@ -27,6 +48,14 @@ var rootCmd = &cobra.Command{
 
 func init() {
 	cli.InitDefaultTetraFlags(rootCmd, defaultCommandTimeout)
+	rootCmd.PersistentFlags().StringVar(&rootFlags.gpsSource, "gps", "radio", "source of the GPS position (radio, nmea:<device>[@<baudrate>], gpsd[:<host>:<port>]), external sources fall back to the radio's GPS")
+	rootCmd.PersistentFlags().StringVar(&rootFlags.recordFilename, "record", "", "record the PEI session into the given file, use --device replay:<filename> to replay it")
+	rootCmd.PersistentFlags().Float64Var(&rootFlags.replaySpeed, "replay-speed", 1, "speed factor for replaying a recorded PEI session (0: as fast as requested)")
+	rootCmd.PersistentFlags().BoolVar(&rootFlags.reconnect, "reconnect", false, "reconnect automatically when the connection to the radio is lost")
+	rootCmd.PersistentFlags().StringSliceVar(&rootFlags.devices, "devices", nil, "use multiple radios simultaneously, given as comma separated list of [<name>=]<device>")
+	rootCmd.PersistentFlags().StringVar(&rootFlags.radioType, "radio-type", string(scanner.AutoDetectRadio), "type of the radio, used to select the command for the cell list (auto, motorola, sepura, hytera, generic)")
+	rootCmd.PersistentFlags().StringVar(&rootFlags.ganProfile, "gan-profile", data.DefaultGANProfile.Name, fmt.Sprintf("name of the GAN profile that defines the RSSI thresholds of the GAN levels (built-in: %s)", strings.Join(builtinGANProfileNames(), ", ")))
+	rootCmd.PersistentFlags().StringVar(&rootFlags.ganProfiles, "gan-profiles", "", "JSON file with user defined GAN profiles (default: gan-profiles.json in the tetra-mess configuration directory)")
 }
 
 func Execute() {
@@ -39,15 +68,143 @@ func Execute() {
 }
 
 func runWithPEI(run func(context.Context, radio.PEI, *cobra.Command, []string)) func(*cobra.Command, []string) {
+	run = withRecording(run)
 	return func(cmd *cobra.Command, args []string) {
 		if strings.ToLower(cli.DefaultTetraFlags.Device) == "demo" {
 			run(cmd.Context(), demo.NewDemo(), cmd, args)
 			return
 		}
+		if strings.HasPrefix(strings.ToLower(cli.DefaultTetraFlags.Device), replayDevicePrefix) {
+			runWithReplay(run)(cmd, args)
+			return
+		}
+		if rootFlags.reconnect {
+			runWithSupervisedPEI(run)(cmd, args)
+			return
+		}
 		cli.RunWithPEI(run, fatal)(cmd, args)
 	}
 }
 
+func runWithSupervisedPEI(run func(context.Context, radio.PEI, *cobra.Command, []string)) func(*cobra.Command, []string) {
+	return func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
+		pei, err := connection.Open(ctx, openSerialPEI(cli.DefaultTetraFlags.Device), initializeRadio, logErrorf)
+		if err != nil {
+			fatalf("cannot open the radio: %v", err)
+		}
+		defer pei.Close()
+
+		run(ctx, pei, cmd, args)
+	}
+}
+
+func runWithReplay(run func(context.Context, radio.PEI, *cobra.Command, []string)) func(*cobra.Command, []string) {
+	return func(cmd *cobra.Command, args []string) {
+		pei, err := openReplay(cli.DefaultTetraFlags.Device[len(replayDevicePrefix):])
+		if err != nil {
+			fatal(err)
+		}
+
+		ctx, cancel := context.WithCancel(cmd.Context())
+		defer cancel()
+		pei.OnDisconnect(cancel)
+
+		run(ctx, pei, cmd, args)
+	}
+}
+
+func openReplay(filename string) (*session.ReplayPEI, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open session file %s: %w", filename, err)
+	}
+	defer file.Close()
+
+	result, err := session.NewReplay(file, rootFlags.replaySpeed)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read session file %s: %w", filename, err)
+	}
+	return result, nil
+}
+
+func withRecording(run func(context.Context, radio.PEI, *cobra.Command, []string)) func(context.Context, radio.PEI, *cobra.Command, []string) {
+	return func(ctx context.Context, pei radio.PEI, cmd *cobra.Command, args []string) {
+		if rootFlags.recordFilename == "" {
+			run(ctx, pei, cmd, args)
+			return
+		}
+
+		file, err := os.Create(rootFlags.recordFilename)
+		if err != nil {
+			fatalf("cannot create session file %s: %v", rootFlags.recordFilename, err)
+		}
+		defer file.Close()
+
+		run(ctx, session.NewRecorder(pei, file), cmd, args)
+	}
+}
+
+// openSerialPEI opens the PEI at the given serial port. Without port name, the first PEI that is found is used.
+func openSerialPEI(portName string) connection.Opener {
+	return func(ctx context.Context) (radio.PEI, error) {
+		if portName != "" {
+			return serial.Open(portName)
+		}
+		foundPortName, err := serial.FindRadioPortName()
+		if err != nil {
+			return nil, err
+		}
+		return serial.Open(foundPortName)
+	}
+}
+
+func initializeRadio(ctx context.Context, pei radio.PEI) error {
+	return pei.ATs(ctx,
+		"ATZ",
+		"ATE0",
+		"AT+CSCS=8859-1",
+	)
+}
+
+func cellListProvider() scanner.CellListProvider {
+	result, err := scanner.NewCellListProvider(scanner.RadioType(rootFlags.radioType))
+	if err != nil {
+		fatal(err)
+	}
+	return result
+}
+
+func positionSource(ctx context.Context, log scanner.Logger) scanner.PositionSource {
+	result, err := scanner.NewPositionSource(ctx, rootFlags.gpsSource, log)
+	if err != nil {
+		fatal(err)
+	}
+	return result
+}
+
+// newScanLoopFactory creates the scan loops for all radios. An external GPS receiver is shared by all radios, but each
+// radio computes speed and heading from its own consecutive fixes.
+func newScanLoopFactory(ctx context.Context, mode string, interval time.Duration, maxInterval time.Duration, distance float64, scanTimeout time.Duration, eventTriggers bool) scanner.ScanLoopFactory {
+	positions := positionSource(ctx, logErrorf)
+	return func(device string, out chan<- scanner.DataPoint, logger scanner.Logger) *scanner.ScanLoop {
+		result := scanner.NewScanLoop(scanSchedule(mode, interval, maxInterval, distance), scanTimeout, scanner.WithMotion(positions), cellListProvider(), out, logger)
+		result.SetDevice(device)
+		if eventTriggers {
+			result.EnableEventTriggers()
+		}
+		return result
+	}
+}
+
+func scanSchedule(mode string, interval time.Duration, maxInterval time.Duration, distance float64) scanner.ScanSchedule {
+	result, err := scanner.NewScanSchedule(scanner.ScanMode(mode), interval, maxInterval, distance)
+	if err != nil {
+		fatal(err)
+	}
+	return result
+}
+
 func fatal(err error) {
 	fmt.Println(err)
 	os.Exit(1)
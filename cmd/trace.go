@@ -5,115 +5,200 @@ import (
 	"fmt"
 	"io"
 	"os"
-	"path/filepath"
-	"strings"
+	"sync"
 	"time"
 
-	"github.com/ftl/tetra-cli/pkg/radio"
 	"github.com/spf13/cobra"
 
+	"github.com/ftl/tetra-mess/pkg/connection"
 	"github.com/ftl/tetra-mess/pkg/data"
+	"github.com/ftl/tetra-mess/pkg/handover"
 	"github.com/ftl/tetra-mess/pkg/scanner"
 )
 
-const defaultTraceScanInterval = 10 * time.Second
+const (
+	defaultTraceScanInterval = 10 * time.Second
+	defaultTraceScanTimeout  = 5 * time.Second
+)
 
 var traceFlags = struct {
-	scanInterval   time.Duration
-	outputFilename string
-	onlyValid      bool
+	scanMode        string
+	scanDistance    float64
+	scanInterval    time.Duration
+	scanMaxInterval time.Duration
+	outputFilename  string
+	onlyValid       bool
+	eventTriggers   bool
+	split           bool
+	handovers       bool
 }{}
 
 var traceCmd = &cobra.Command{
 	Use:   "trace [output filename]",
 	Short: "Trace the signal strength and the GPS position and save it to a file",
 	Long: `Trace the signal strength and the GPS position and save it to a file
-The output file can be in CSV or JSON format, depending on the file extension.`,
-	Run: runWithPEI(runTrace), // do not use runWithRadioAndTimeout here, because we want to run the command indefinitely
+The output file can be in CSV or JSON format, depending on the file extension. With the additional extension .gz or .zst,
+the output file is compressed with gzip or zstd, e.g. trace.csv.gz.
+With multiple devices, all data points are written into one trace, tagged with the device name, or into one file per device using --split.
+With --handovers, handovers and changes of the best server are additionally written into <output filename>-handovers.json.`,
+	Run: runWithDevices(runTrace), // do not use runWithRadioAndTimeout here, because we want to run the command indefinitely
 }
 
 func init() {
-	traceCmd.Flags().DurationVar(&traceFlags.scanInterval, "scan-interval", defaultTraceScanInterval, "scan interval")
+	traceCmd.Flags().StringVar(&traceFlags.scanMode, "scan-mode", string(scanner.TimeScanMode), "scan mode (time, distance, adaptive)")
+	traceCmd.Flags().DurationVar(&traceFlags.scanInterval, "scan-interval", defaultTraceScanInterval, "scan interval in time mode")
+	traceCmd.Flags().DurationVar(&traceFlags.scanMaxInterval, "scan-max-interval", defaultScanMaxInterval, "maximum scan interval in distance and adaptive mode, also when standing still")
+	traceCmd.Flags().Float64Var(&traceFlags.scanDistance, "scan-distance", defaultScanDistance, "distance between two scans in metres (in distance and adaptive mode)")
+	traceCmd.Flags().BoolVar(&traceFlags.eventTriggers, "events", false, "additionally scan immediately when the radio changes the cell or acquires a GPS fix")
+	traceCmd.Flags().BoolVar(&traceFlags.split, "split", false, "write one file per device when using multiple devices, the device name is appended to the filename")
+	traceCmd.Flags().BoolVar(&traceFlags.handovers, "handovers", false, "write handovers and changes of the best server into a separate event file")
 	traceCmd.Flags().BoolVar(&traceFlags.onlyValid, "only-valid", false, "output only valid data points (with GPS position and RSSI/Cx values)")
 
+	addMetadataFlags(traceCmd)
+
 	traceCmd.Flags().MarkHidden("output")
 
 	rootCmd.AddCommand(traceCmd)
 }
 
-func runTrace(ctx context.Context, pei radio.PEI, cmd *cobra.Command, args []string) {
+func runTrace(ctx context.Context, devices []scanner.Device, cmd *cobra.Command, args []string) {
 	if len(args) != 1 {
 		cmd.Help()
 		return
 	}
 
 	outputFilename := args[0]
+	encoder, writeHeader, err := traceEncoder(outputFilename)
+	if err != nil {
+		fatal(err)
+	}
+
+	for _, device := range devices {
+		if _, supervised := connection.AsSupervised(device.PEI); supervised {
+			continue // the supervisor initializes the radio after every (re)connect
+		}
+		err := initializeRadio(ctx, device.PEI)
+		if err != nil {
+			fatalf("cannot initilize radio %s: %v", device.Name, err)
+		}
+	}
+	metadata := traceMetadata(ctx, devices, logErrorf)
+	metadata.Started = time.Now().UTC()
+
+	outputs := make(map[string]io.Writer, len(devices))
 	var out io.Writer
-	if outputFilename != "" {
-		file, err := os.Create(traceFlags.outputFilename)
+	for _, device := range devices {
+		if out != nil && !traceFlags.split {
+			outputs[device.Name] = out
+			continue
+		}
+
+		filename := outputFilename
+		if traceFlags.split {
+			filename = filenameForDevice(outputFilename, device.Name)
+		}
+		file, err := data.CreateFile(filename)
+		if err != nil {
+			fatalf("cannot create output file %s: %v", filename, err)
+		}
+		defer file.Close()
+		err = writeHeader(file, metadata)
 		if err != nil {
-			fatalf("cannot create output file %s: %v", traceFlags.outputFilename, err)
+			fatalf("cannot write header to output file %s: %v", filename, err)
 		}
 		out = file
-	} else {
-		out = os.Stdout
+		outputs[device.Name] = out
 	}
 
-	format := TraceOutputFormat(strings.ToLower(filepath.Ext(outputFilename)))
-	var encoder func(data.DataPoint) string
-	switch format {
-	case "csv":
-		encoder = data.DataPointToCSV
-	case "json":
-		encoder = data.DataPointToJSON
-	default:
-		fatalf("unknown output format: %s", format)
+	var handovers io.Writer
+	if traceFlags.handovers {
+		filename := handover.Filename(outputFilename)
+		file, err := os.Create(filename)
+		if err != nil {
+			fatalf("cannot create handover file %s: %v", filename, err)
+		}
+		defer file.Close()
+		handovers = file
 	}
 
 	onlyValid := traceFlags.onlyValid
+	newScanLoop := newScanLoopFactory(ctx, traceFlags.scanMode, traceFlags.scanInterval, traceFlags.scanMaxInterval, traceFlags.scanDistance, defaultTraceScanTimeout, traceFlags.eventTriggers)
+
+	radioData := make(chan scanner.DataPoint, len(devices))
+	outages := make(chan deviceOutage, len(devices))
+	loops := &sync.WaitGroup{}
+	for _, device := range devices {
+		if supervised, ok := connection.AsSupervised(device.PEI); ok {
+			supervised.OnOutage(func(outage connection.Outage) {
+				select {
+				case outages <- deviceOutage{device: device.Name, Outage: outage}:
+				case <-ctx.Done():
+				}
+			})
+		}
 
-	err := pei.ATs(ctx,
-		"ATZ",
-		"ATE0",
-		"AT+CSCS=8859-1",
-	)
-	if err != nil {
-		fatalf("cannot initilize radio: %v", err)
+		loop := newScanLoop(device.Name, radioData, logErrorf)
+		loops.Add(1)
+		go func() {
+			defer loops.Done()
+			loop.Run(ctx, device.PEI)
+		}()
 	}
 
 	closed := make(chan struct{})
 	go func() {
 		defer close(closed)
+		loops.Wait()
+	}()
 
-		scanTicker := time.NewTicker(traceFlags.scanInterval)
-		defer scanTicker.Stop()
+	writeTrace(ctx, closed, radioData, outages, outputs, handovers, encoder, onlyValid)
+}
 
-		for {
-			select {
-			case <-ctx.Done():
-				return
-			case <-scanTicker.C:
-				scanForTrace(ctx, pei, out, encoder, onlyValid)
-			}
-		}
-	}()
+type TraceOutputFormat string
 
-	select {
-	case <-ctx.Done():
-	case <-closed:
+// traceEncoder returns the encoder for the data points and the header writer that fit to the format of the given
+// trace file.
+func traceEncoder(filename string) (func(data.DataPoint) string, func(io.Writer, data.Metadata) error, error) {
+	format := TraceOutputFormat(data.FileFormat(filename))
+	switch format {
+	case "csv":
+		return data.DataPointToCSV, data.WriteCSVHeader, nil
+	case "json":
+		return data.DataPointToJSON, data.WriteJSONHeader, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown output format: %s", format)
 	}
+}
 
-	closer, ok := out.(io.Closer)
-	if ok {
-		closer.Close()
-	}
+type deviceOutage struct {
+	connection.Outage
+	device string
 }
 
-type TraceOutputFormat string
+func writeTrace(ctx context.Context, closed <-chan struct{}, radioData <-chan scanner.DataPoint, outages <-chan deviceOutage, outputs map[string]io.Writer, handovers io.Writer, encoder func(data.DataPoint) string, onlyValid bool) {
+	detector := handover.NewDetector()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-closed:
+			return
+		case rd := <-radioData:
+			writeTraceDataPoints(outputs[rd.Device], encoder, rd.Measurement.DataPoints, onlyValid)
+			if handovers != nil {
+				writeHandovers(handovers, detector.Add(rd.Measurement))
+			}
+		case outage := <-outages:
+			_, err := fmt.Fprintln(outputs[outage.device], data.OutageComment(outage.device, outage.Start, outage.End))
+			if err != nil {
+				logErrorf("error writing outage: %v", err)
+			}
+		}
+	}
+}
 
-func scanForTrace(ctx context.Context, pei radio.PEI, out io.Writer, encoder func(data.DataPoint) string, onlyValid bool) {
-	_, datapoints := scanner.ScanSignalAndPosition(ctx, pei, logErrorf)
-	for _, dataPoint := range datapoints {
+func writeTraceDataPoints(out io.Writer, encoder func(data.DataPoint) string, dataPoints []data.DataPoint, onlyValid bool) {
+	for _, dataPoint := range dataPoints {
 		if onlyValid && !dataPoint.IsValid() {
 			continue
 		}
@@ -127,3 +212,13 @@ func scanForTrace(ctx context.Context, pei radio.PEI, out io.Writer, encoder fun
 		}
 	}
 }
+
+func writeHandovers(out io.Writer, events []handover.Event) {
+	for _, event := range events {
+		_, err := fmt.Fprintln(out, handover.EventToJSON(event))
+		if err != nil {
+			logErrorf("error writing handover: %v", err)
+			return
+		}
+	}
+}
@@ -2,13 +2,15 @@ package cmd
 
 import (
 	"context"
+	"strings"
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/ftl/tetra-cli/pkg/cli"
-	"github.com/ftl/tetra-cli/pkg/radio"
 	"github.com/spf13/cobra"
 
+	"github.com/ftl/tetra-mess/pkg/data"
+	"github.com/ftl/tetra-mess/pkg/scanner"
 	"github.com/ftl/tetra-mess/pkg/tui"
 )
 
@@ -18,31 +20,59 @@ const (
 )
 
 var tuiFlags = struct {
-	scanInterval time.Duration
-	outputDir    string
-	outputFormat string
+	scanMode        string
+	scanDistance    float64
+	scanInterval    time.Duration
+	scanMaxInterval time.Duration
+	eventTriggers   bool
+	outputDir       string
+	outputFormat    string
 }{}
 
 var tuiCmd = &cobra.Command{
 	Use:   "tui",
 	Short: "Start the TUI to monitor measurement data and control tracing",
-	Run:   runWithPEI(runTUI),
+	Run:   runWithDevices(runTUI),
 }
 
 func init() {
-	tuiCmd.Flags().DurationVar(&tuiFlags.scanInterval, "scan-interval", defaultTUIScanInterval, "scan interval")
+	tuiCmd.Flags().StringVar(&tuiFlags.scanMode, "scan-mode", string(scanner.TimeScanMode), "scan mode (time, distance, adaptive)")
+	tuiCmd.Flags().DurationVar(&tuiFlags.scanInterval, "scan-interval", defaultTUIScanInterval, "scan interval in time mode")
+	tuiCmd.Flags().DurationVar(&tuiFlags.scanMaxInterval, "scan-max-interval", defaultScanMaxInterval, "maximum scan interval in distance and adaptive mode, also when standing still")
+	tuiCmd.Flags().Float64Var(&tuiFlags.scanDistance, "scan-distance", defaultScanDistance, "distance between two scans in metres (in distance and adaptive mode)")
+	tuiCmd.Flags().BoolVar(&tuiFlags.eventTriggers, "events", false, "additionally scan immediately when the radio changes the cell or acquires a GPS fix")
 	tuiCmd.Flags().StringVar(&tuiFlags.outputDir, "output", "", "output directory for trace files")
-	tuiCmd.Flags().StringVar(&tuiFlags.outputFormat, "format", "csv", "output format for trace files (csv, json)")
+	tuiCmd.Flags().StringVar(&tuiFlags.outputFormat, "format", "csv", "output format for trace files (csv, json), append .gz or .zst for compressed files, e.g. csv.gz")
+
+	addMetadataFlags(tuiCmd)
 
 	rootCmd.AddCommand(tuiCmd)
 }
 
-func runTUI(ctx context.Context, pei radio.PEI, cmd *cobra.Command, args []string) {
+func runTUI(ctx context.Context, devices []scanner.Device, cmd *cobra.Command, args []string) {
+	deviceNames := make([]string, len(devices))
+	for i, device := range devices {
+		deviceNames[i] = device.Name
+	}
+	statusText := cli.DefaultTetraFlags.Device
+	if len(rootFlags.devices) > 0 {
+		statusText = strings.Join(deviceNames, ", ")
+	}
+
+	profile, err := ganProfile()
+	if err != nil {
+		fatal(err)
+	}
+
 	// UI
-	mainScreen := tui.NewMainScreen(version, cli.DefaultTetraFlags.Device)
+	mainScreen := tui.NewMainScreen(version, statusText, deviceNames, profile)
 	ui := tea.NewProgram(mainScreen, tea.WithAltScreen())
 
-	app, err := tui.NewApp(ctx, ui, pei, tuiFlags.outputDir, tuiFlags.outputFormat, tuiFlags.scanInterval, defaultTUIScanTimeout)
+	newScanLoop := newScanLoopFactory(ctx, tuiFlags.scanMode, tuiFlags.scanInterval, tuiFlags.scanMaxInterval, tuiFlags.scanDistance, defaultTUIScanTimeout, tuiFlags.eventTriggers)
+	collectMetadata := func(ctx context.Context, log scanner.Logger) data.Metadata {
+		return traceMetadata(ctx, devices, log)
+	}
+	app, err := tui.NewApp(ctx, ui, devices, newScanLoop, tuiFlags.outputDir, tuiFlags.outputFormat, collectMetadata)
 	if err != nil {
 		fatalf("error creating the app: %v", err)
 	}
@@ -13,6 +13,8 @@ require (
 	github.com/ftl/tetra-cli v1.1.0
 	github.com/ftl/tetra-pei v1.4.3
 	github.com/im7mortal/UTM v1.4.0
+	github.com/jacobsa/go-serial v0.0.0-20180131005756-15cf729a72d4
+	github.com/klauspost/compress v1.18.0
 	github.com/spf13/cobra v1.9.1
 	github.com/tkrajina/gpxgo v1.4.0
 	github.com/twpayne/go-kml/v3 v3.3.0
@@ -27,7 +29,6 @@ require (
 	github.com/erikgeiser/coninput v0.0.0-20211004153227-1c3628e74d0f // indirect
 	github.com/hedhyw/Go-Serial-Detector v1.0.0-rc1 // indirect
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
-	github.com/jacobsa/go-serial v0.0.0-20180131005756-15cf729a72d4 // indirect
 	github.com/lucasb-eyer/go-colorful v1.2.0 // indirect
 	github.com/mattn/go-isatty v0.0.20 // indirect
 	github.com/mattn/go-localereader v0.0.1 // indirect
@@ -0,0 +1,356 @@
+package connection
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/ftl/tetra-cli/pkg/radio"
+)
+
+const (
+	DefaultMinBackoff = 1 * time.Second
+	DefaultMaxBackoff = 30 * time.Second
+)
+
+var ErrDisconnected = errors.New("radio disconnected")
+
+// Opener opens a new connection to the PEI of the radio.
+type Opener func(ctx context.Context) (radio.PEI, error)
+
+// Initializer prepares the radio every time after the connection was opened.
+type Initializer func(ctx context.Context, pei radio.PEI) error
+
+type Logger func(string, ...any)
+
+// Supervised is implemented by PEIs that reconnect automatically.
+type Supervised interface {
+	OnOutage(func(Outage))
+	SetLogger(Logger)
+	AddInitializer(Initializer)
+}
+
+// Wrapper is implemented by PEIs that wrap another PEI, e.g. to record the session.
+type Wrapper interface {
+	Unwrap() radio.PEI
+}
+
+// AsSupervised finds the supervised PEI within the given PEI and all the PEIs it wraps.
+func AsSupervised(pei radio.PEI) (Supervised, bool) {
+	for pei != nil {
+		if supervised, ok := pei.(Supervised); ok {
+			return supervised, true
+		}
+		wrapper, ok := pei.(Wrapper)
+		if !ok {
+			break
+		}
+		pei = wrapper.Unwrap()
+	}
+	return nil, false
+}
+
+// Outage describes the time window in which the radio was not connected.
+type Outage struct {
+	Start time.Time
+	End   time.Time
+}
+
+func (o Outage) Duration() time.Duration {
+	return o.End.Sub(o.Start)
+}
+
+// SupervisedPEI keeps the connection to the radio alive. When the connection is lost, it reopens the PEI with an
+// increasing backoff and runs the initialization again. While the radio is disconnected, all requests fail with
+// ErrDisconnected. The disconnect callback is only called when the SupervisedPEI is closed.
+type SupervisedPEI struct {
+	ctx        context.Context
+	open       Opener
+	initialize Initializer
+	logger     Logger
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+
+	mutex              sync.RWMutex
+	pei                radio.PEI
+	indications        []indicationConfig
+	initializers       []Initializer
+	outageCallback     func(Outage)
+	disconnectCallback func()
+	closing            bool
+	closed             chan struct{}
+}
+
+type indicationConfig struct {
+	prefix        string
+	trailingLines int
+	handler       func(lines []string)
+}
+
+// Open opens the first connection to the radio. This first connection must succeed, otherwise an error is returned.
+// The connection is supervised until the given context is done or the SupervisedPEI is closed.
+func Open(ctx context.Context, open Opener, initialize Initializer, logger Logger) (*SupervisedPEI, error) {
+	result := &SupervisedPEI{
+		ctx:        ctx,
+		open:       open,
+		initialize: initialize,
+		logger:     logger,
+		MinBackoff: DefaultMinBackoff,
+		MaxBackoff: DefaultMaxBackoff,
+		closed:     make(chan struct{}),
+	}
+
+	err := result.connect()
+	if err != nil {
+		return nil, err
+	}
+
+	go func() {
+		<-ctx.Done()
+		result.Close()
+	}()
+
+	return result, nil
+}
+
+func (p *SupervisedPEI) connect() error {
+	pei, err := p.open(p.ctx)
+	if err != nil {
+		return err
+	}
+	// the handler ignores the PEI until it is published, a disconnect before is detected below
+	pei.OnDisconnect(func() {
+		p.handleDisconnect(pei)
+	})
+
+	p.mutex.Lock()
+	for _, indication := range p.indications {
+		pei.AddIndication(indication.prefix, indication.trailingLines, indication.handler)
+	}
+	initializers := p.initializers
+	p.mutex.Unlock()
+
+	if p.initialize != nil {
+		initializers = append([]Initializer{p.initialize}, initializers...)
+	}
+	for _, initialize := range initializers {
+		err = initialize(p.ctx, pei)
+		if err != nil {
+			pei.Close()
+			return err
+		}
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.closing || pei.Closed() {
+		pei.Close()
+		return ErrDisconnected
+	}
+	p.pei = pei
+
+	return nil
+}
+
+func (p *SupervisedPEI) handleDisconnect(pei radio.PEI) {
+	p.mutex.Lock()
+	if p.pei != pei {
+		p.mutex.Unlock()
+		return
+	}
+	p.pei = nil
+	closing := p.closing
+	disconnectCallback := p.disconnectCallback
+	p.mutex.Unlock()
+
+	if closing {
+		p.finishClose(disconnectCallback)
+		return
+	}
+
+	go p.reconnect(time.Now())
+}
+
+func (p *SupervisedPEI) reconnect(start time.Time) {
+	p.log("connection to the radio lost, reconnecting...")
+
+	backoff := p.MinBackoff
+	for {
+		select {
+		case <-p.ctx.Done():
+			return
+		case <-p.closed:
+			return
+		case <-time.After(backoff):
+		}
+
+		err := p.connect()
+		if err == nil {
+			break
+		}
+		if p.isClosing() {
+			return
+		}
+		backoff = min(2*backoff, p.MaxBackoff)
+		p.log("cannot reconnect to the radio, retrying in %v: %v", backoff, err)
+	}
+
+	outage := Outage{Start: start, End: time.Now()}
+	p.log("reconnected to the radio after %v", outage.Duration().Round(time.Second))
+
+	p.mutex.RLock()
+	outageCallback := p.outageCallback
+	p.mutex.RUnlock()
+	if outageCallback != nil {
+		outageCallback(outage)
+	}
+}
+
+func (p *SupervisedPEI) SetLogger(logger Logger) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.logger = logger
+}
+
+// OnOutage registers a callback that is called every time the connection to the radio was restored.
+func (p *SupervisedPEI) OnOutage(callback func(Outage)) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.outageCallback = callback
+}
+
+// AddInitializer registers an additional initialization that runs every time the connection was reopened, after the
+// initialization given to Open. It does not run for the current connection. Use it to restore settings that are reset
+// by the initialization, e.g. unsolicited indications that are disabled by ATZ.
+func (p *SupervisedPEI) AddInitializer(initialize Initializer) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.initializers = append(p.initializers, initialize)
+}
+
+func (p *SupervisedPEI) Close() {
+	p.mutex.Lock()
+	if p.closing {
+		p.mutex.Unlock()
+		return
+	}
+	p.closing = true
+	pei := p.pei
+	disconnectCallback := p.disconnectCallback
+	p.mutex.Unlock()
+
+	if pei != nil {
+		// the disconnect handler finishes closing
+		pei.Close()
+		return
+	}
+	p.finishClose(disconnectCallback)
+}
+
+func (p *SupervisedPEI) finishClose(disconnectCallback func()) {
+	select {
+	case <-p.closed:
+		return
+	default:
+		close(p.closed)
+	}
+	if disconnectCallback != nil {
+		disconnectCallback()
+	}
+}
+
+func (p *SupervisedPEI) isClosing() bool {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	return p.closing
+}
+
+func (p *SupervisedPEI) Closed() bool {
+	select {
+	case <-p.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+func (p *SupervisedPEI) WaitUntilClosed(ctx context.Context) {
+	select {
+	case <-p.closed:
+	case <-ctx.Done():
+	}
+}
+
+func (p *SupervisedPEI) OnDisconnect(callback func()) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.disconnectCallback = callback
+}
+
+func (p *SupervisedPEI) AddIndication(prefix string, trailingLines int, handler func(lines []string)) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.indications = append(p.indications, indicationConfig{
+		prefix:        prefix,
+		trailingLines: trailingLines,
+		handler:       handler,
+	})
+	if p.pei == nil {
+		return nil
+	}
+	return p.pei.AddIndication(prefix, trailingLines, handler)
+}
+
+func (p *SupervisedPEI) ClearSyntaxErrors(ctx context.Context) error {
+	pei, err := p.current()
+	if err != nil {
+		return err
+	}
+	return pei.ClearSyntaxErrors(ctx)
+}
+
+func (p *SupervisedPEI) Request(ctx context.Context, request string) ([]string, error) {
+	pei, err := p.current()
+	if err != nil {
+		return nil, err
+	}
+	return pei.Request(ctx, request)
+}
+
+func (p *SupervisedPEI) AT(ctx context.Context, request string) ([]string, error) {
+	pei, err := p.current()
+	if err != nil {
+		return nil, err
+	}
+	return pei.AT(ctx, request)
+}
+
+func (p *SupervisedPEI) ATs(ctx context.Context, requests ...string) error {
+	pei, err := p.current()
+	if err != nil {
+		return err
+	}
+	return pei.ATs(ctx, requests...)
+}
+
+func (p *SupervisedPEI) current() (radio.PEI, error) {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	if p.pei == nil {
+		return nil, ErrDisconnected
+	}
+	return p.pei, nil
+}
+
+func (p *SupervisedPEI) log(format string, args ...any) {
+	p.mutex.RLock()
+	logger := p.logger
+	p.mutex.RUnlock()
+
+	if logger == nil {
+		return
+	}
+	logger(format, args...)
+}
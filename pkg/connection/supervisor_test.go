@@ -0,0 +1,174 @@
+package connection
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ftl/tetra-cli/pkg/radio"
+)
+
+// fakePEI records the requests and can be disconnected.
+type fakePEI struct {
+	radio.PEI
+
+	mutex        sync.Mutex
+	requests     []string
+	closed       bool
+	onDisconnect func()
+}
+
+func (p *fakePEI) AT(ctx context.Context, request string) ([]string, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.requests = append(p.requests, request)
+	return nil, nil
+}
+
+func (p *fakePEI) AddIndication(string, int, func([]string)) error {
+	return nil
+}
+
+func (p *fakePEI) OnDisconnect(callback func()) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.onDisconnect = callback
+}
+
+func (p *fakePEI) Closed() bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.closed
+}
+
+func (p *fakePEI) Close() {
+	p.mutex.Lock()
+	if p.closed {
+		p.mutex.Unlock()
+		return
+	}
+	p.closed = true
+	onDisconnect := p.onDisconnect
+	p.mutex.Unlock()
+	if onDisconnect != nil {
+		onDisconnect()
+	}
+}
+
+func (p *fakePEI) Requests() []string {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return slices.Clone(p.requests)
+}
+
+func TestReconnectRunsInitializers(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	opened := make(chan *fakePEI, 2)
+	open := func(context.Context) (radio.PEI, error) {
+		pei := &fakePEI{}
+		opened <- pei
+		return pei, nil
+	}
+	initialize := func(ctx context.Context, pei radio.PEI) error {
+		_, err := pei.AT(ctx, "ATZ")
+		return err
+	}
+
+	supervised, err := Open(ctx, open, initialize, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer supervised.Close()
+	supervised.MinBackoff = time.Millisecond
+	reconnected := make(chan Outage, 1)
+	supervised.OnOutage(func(outage Outage) {
+		reconnected <- outage
+	})
+	supervised.AddInitializer(func(ctx context.Context, pei radio.PEI) error {
+		_, err := pei.AT(ctx, "AT+CREG=1")
+		return err
+	})
+
+	first := <-opened
+	if requests := first.Requests(); !slices.Equal(requests, []string{"ATZ"}) {
+		t.Errorf("unexpected initialization of the first connection: %v", requests)
+	}
+
+	first.Close()
+	select {
+	case <-reconnected:
+	case <-time.After(time.Second):
+		t.Fatal("no reconnect")
+	}
+	second := <-opened
+	if requests := second.Requests(); !slices.Equal(requests, []string{"ATZ", "AT+CREG=1"}) {
+		t.Errorf("unexpected initialization after reconnect: %v", requests)
+	}
+
+	_, err = supervised.AT(ctx, "AT+CSQ")
+	if err != nil {
+		t.Errorf("the reconnected PEI is not used: %v", err)
+	}
+}
+
+func TestReconnectLogsTheNextBackoff(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	attempts := 0
+	var first *fakePEI
+	open := func(context.Context) (radio.PEI, error) {
+		attempts++
+		switch attempts {
+		case 1:
+			first = &fakePEI{}
+			return first, nil
+		case 2, 3:
+			return nil, errors.New("no device")
+		default:
+			return &fakePEI{}, nil
+		}
+	}
+	var mutex sync.Mutex
+	var messages []string
+	logger := func(format string, args ...any) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		messages = append(messages, fmt.Sprintf(format, args...))
+	}
+
+	supervised, err := Open(ctx, open, nil, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer supervised.Close()
+	supervised.MinBackoff = time.Millisecond
+	reconnected := make(chan Outage, 1)
+	supervised.OnOutage(func(outage Outage) {
+		reconnected <- outage
+	})
+
+	first.Close()
+	select {
+	case <-reconnected:
+	case <-time.After(time.Second):
+		t.Fatal("no reconnect")
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	expected := []string{
+		"connection to the radio lost, reconnecting...",
+		"cannot reconnect to the radio, retrying in 2ms: no device",
+		"cannot reconnect to the radio, retrying in 4ms: no device",
+	}
+	if len(messages) < len(expected) || !slices.Equal(messages[:len(expected)], expected) {
+		t.Errorf("expected the log messages\n%v\ngot\n%v", expected, messages)
+	}
+}
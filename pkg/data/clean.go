@@ -0,0 +1,208 @@
+package data
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// GPSIssue describes why the position of a data point is not plausible.
+type GPSIssue int
+
+const (
+	NoGPSIssue      GPSIssue = 0
+	ZeroPosition    GPSIssue = 1
+	StaleFix        GPSIssue = 2
+	ImpossibleSpeed GPSIssue = 3
+)
+
+func (i GPSIssue) String() string {
+	switch i {
+	case ZeroPosition:
+		return "zero position"
+	case StaleFix:
+		return "stale fix"
+	case ImpossibleSpeed:
+		return "impossible speed"
+	default:
+		return ""
+	}
+}
+
+// CleanMode defines how the Cleaner handles data points with implausible positions.
+type CleanMode string
+
+const (
+	// CleanDrop drops data points with implausible positions.
+	CleanDrop CleanMode = "drop"
+	// CleanFlag keeps data points with implausible positions, but sets their GPSIssue.
+	CleanFlag CleanMode = "flag"
+	// CleanInterpolate interpolates the positions of data points with implausible positions between the enclosing
+	// plausible fixes. Data points that cannot be interpolated are dropped, except the data points at the end of the
+	// sequence: they are returned with their GPSIssue set by Flush.
+	CleanInterpolate CleanMode = "interpolate"
+)
+
+var CleanModes = []CleanMode{CleanDrop, CleanFlag, CleanInterpolate}
+
+func ParseCleanMode(s string) (CleanMode, error) {
+	mode := CleanMode(strings.ToLower(s))
+	switch mode {
+	case CleanDrop, CleanFlag, CleanInterpolate:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("invalid clean mode: %s", s)
+	}
+}
+
+// DefaultCleanMaxSpeed is the default maximum plausible speed of the Cleaner in m/s (300 km/h).
+const DefaultCleanMaxSpeed = 300 / 3.6
+
+// DefaultCleanMaxGap is the default maximum time a position may be repeated or interpolated.
+const DefaultCleanMaxGap = time.Minute
+
+// the number of consecutive speed outliers after which the cleaner assumes that the reference fix was the outlier
+const maxSpeedOutliers = 3
+
+// Cleaner detects implausible GPS positions in a chronological sequence of data points:
+//   - zero positions (0/0), e.g. data points that were recorded without GPS fix.
+//   - stale fixes, i.e. positions without satellites, or the same position repeated for longer than maxGap.
+//   - positions that can only be reached from the previous fix with a speed above maxSpeed (in m/s).
+//
+// The Cleaner keeps state between calls of Filter, hence one Cleaner must only be used for one sequence of data
+// points. In the interpolate mode, data points with implausible positions are held back until the next plausible
+// fix is available, call Flush at the end of the sequence to get the remaining ones.
+type Cleaner struct {
+	mode     CleanMode
+	maxSpeed float64
+	maxGap   time.Duration
+
+	reference      Position
+	referenceSince time.Time
+	outliers       int
+
+	lastMeasurementID string
+	lastIssue         GPSIssue
+
+	pending     []DataPoint
+	pendingFrom Position
+}
+
+func NewCleaner(mode CleanMode, maxSpeed float64, maxGap time.Duration) *Cleaner {
+	return &Cleaner{
+		mode:     mode,
+		maxSpeed: maxSpeed,
+		maxGap:   maxGap,
+	}
+}
+
+func (c *Cleaner) Filter(dataPoints []DataPoint) []DataPoint {
+	result := make([]DataPoint, 0, len(dataPoints))
+	for _, dataPoint := range dataPoints {
+		result = append(result, c.add(dataPoint)...)
+	}
+	return result
+}
+
+func (c *Cleaner) add(dataPoint DataPoint) []DataPoint {
+	issue := c.issueOf(dataPoint)
+	if issue == NoGPSIssue {
+		result := c.interpolatePending(dataPoint.Position())
+		return append(result, dataPoint)
+	}
+
+	switch c.mode {
+	case CleanFlag:
+		dataPoint.GPSIssue = issue
+		return []DataPoint{dataPoint}
+	case CleanInterpolate:
+		if len(c.pending) == 0 {
+			c.pendingFrom = c.reference
+		}
+		// the issue is reset when the position is interpolated
+		dataPoint.GPSIssue = issue
+		c.pending = append(c.pending, dataPoint)
+		return nil
+	default:
+		return nil
+	}
+}
+
+// Flush returns the data points that are held back in the interpolate mode at the end of the sequence. Their
+// positions cannot be interpolated without a following plausible fix, hence they are returned flagged.
+func (c *Cleaner) Flush() []DataPoint {
+	result := c.pending
+	c.pending = nil
+	return result
+}
+
+// issueOf checks the position of the given data point. All data points of the same measurement share the same
+// position, so the position is checked only once per measurement.
+func (c *Cleaner) issueOf(dataPoint DataPoint) GPSIssue {
+	measurementID := dataPoint.MeasurementID()
+	if measurementID == c.lastMeasurementID {
+		return c.lastIssue
+	}
+	c.lastMeasurementID = measurementID
+	c.lastIssue = c.Check(dataPoint.Position())
+	return c.lastIssue
+}
+
+// Check checks if the given position is plausible, compared to the previous plausible fix. Plausible positions
+// become the reference for the following checks.
+func (c *Cleaner) Check(position Position) GPSIssue {
+	if position.Latitude == 0 && position.Longitude == 0 {
+		return ZeroPosition
+	}
+	if !position.HasFix() {
+		return StaleFix
+	}
+
+	if c.reference.HasFix() {
+		samePosition := position.Latitude == c.reference.Latitude && position.Longitude == c.reference.Longitude
+		if samePosition && position.Timestamp.Sub(c.referenceSince) > c.maxGap {
+			return StaleFix
+		}
+
+		speed, ok := c.reference.SpeedTo(position)
+		if ok && speed > c.maxSpeed {
+			c.outliers++
+			if c.outliers <= maxSpeedOutliers {
+				return ImpossibleSpeed
+			}
+		}
+
+		if samePosition {
+			c.reference.Timestamp = position.Timestamp
+			c.outliers = 0
+			return NoGPSIssue
+		}
+	}
+
+	c.reference = position
+	c.referenceSince = position.Timestamp
+	c.outliers = 0
+	return NoGPSIssue
+}
+
+// interpolatePending interpolates the positions of the pending data points between the plausible fix before them
+// and the given next plausible fix. If there is no fix before them or the fixes are too far apart, the pending
+// data points are dropped.
+func (c *Cleaner) interpolatePending(next Position) []DataPoint {
+	if len(c.pending) == 0 {
+		return nil
+	}
+	pending := c.pending
+	c.pending = nil
+
+	previous := c.pendingFrom
+	if !previous.HasFix() || next.Timestamp.Sub(previous.Timestamp) > c.maxGap {
+		return nil
+	}
+
+	result := make([]DataPoint, 0, len(pending))
+	for _, dataPoint := range pending {
+		result = append(result, dataPoint.WithPosition(InterpolatePosition(previous, next, dataPoint.Timestamp)))
+	}
+	return result
+}
@@ -0,0 +1,108 @@
+package data
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestCleaner(t *testing.T) {
+	start := time.Date(2026, 1, 2, 10, 0, 0, 0, time.UTC)
+	at := func(seconds int, lat, lon float64, sats int) DataPoint {
+		return DataPoint{Latitude: lat, Longitude: lon, Satellites: sats, Timestamp: start.Add(time.Duration(seconds) * time.Second), LAC: 100, RSSI: -80}
+	}
+	sequence := []DataPoint{
+		at(0, 52.000, 13.0, 7),
+		at(10, 52.001, 13.0, 7),
+		at(20, 0, 0, 0),          // zero position
+		at(25, 52.0015, 13.0, 0), // no satellites
+		at(30, 52.500, 13.0, 7),  // 55km in 10s
+		at(40, 52.003, 13.0, 7),
+		at(50, 0, 0, 0), // zero position at the end
+	}
+	flagged := func(dataPoint DataPoint, issue GPSIssue) DataPoint {
+		dataPoint.GPSIssue = issue
+		return dataPoint
+	}
+
+	tt := []struct {
+		mode     CleanMode
+		expected []DataPoint
+	}{
+		{
+			mode: CleanDrop,
+			expected: []DataPoint{
+				sequence[0],
+				sequence[1],
+				sequence[5],
+			},
+		},
+		{
+			mode: CleanFlag,
+			expected: []DataPoint{
+				sequence[0],
+				sequence[1],
+				flagged(sequence[2], ZeroPosition),
+				flagged(sequence[3], StaleFix),
+				flagged(sequence[4], ImpossibleSpeed),
+				sequence[5],
+				flagged(sequence[6], ZeroPosition),
+			},
+		},
+		{
+			mode: CleanInterpolate,
+			expected: []DataPoint{
+				sequence[0],
+				sequence[1],
+				at(20, 52.0016667, 13.0, 7),
+				at(25, 52.0020, 13.0, 7),
+				at(30, 52.0023333, 13.0, 7),
+				sequence[5],
+				flagged(sequence[6], ZeroPosition),
+			},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(string(tc.mode), func(t *testing.T) {
+			cleaner := NewCleaner(tc.mode, DefaultCleanMaxSpeed, DefaultCleanMaxGap)
+			var actual []DataPoint
+			for _, dataPoint := range sequence {
+				actual = append(actual, cleaner.Filter([]DataPoint{dataPoint})...)
+			}
+			actual = append(actual, Flush(cleaner)...)
+
+			if len(actual) != len(tc.expected) {
+				t.Fatalf("expected %d data points, got %d: %v", len(tc.expected), len(actual), actual)
+			}
+			for i, e := range tc.expected {
+				a := actual[i]
+				if !a.Timestamp.Equal(e.Timestamp) || math.Abs(a.Latitude-e.Latitude) > 1e-6 || math.Abs(a.Longitude-e.Longitude) > 1e-6 || a.GPSIssue != e.GPSIssue {
+					t.Errorf("%d: expected %v %f,%f %q, got %v %f,%f %q", i, e.Timestamp, e.Latitude, e.Longitude, e.GPSIssue, a.Timestamp, a.Latitude, a.Longitude, a.GPSIssue)
+				}
+			}
+		})
+	}
+}
+
+func TestFiltersFlushPassesHeldBackDataPointsThroughFollowingFilters(t *testing.T) {
+	start := time.Date(2026, 1, 2, 10, 0, 0, 0, time.UTC)
+	cleaner := NewCleaner(CleanInterpolate, DefaultCleanMaxSpeed, DefaultCleanMaxGap)
+	filter := Filters(cleaner, Predicate(func(dp DataPoint) bool { return dp.LAC == 100 }))
+
+	filter.Filter([]DataPoint{{Latitude: 52, Longitude: 13, Satellites: 7, Timestamp: start, LAC: 100}})
+	held := filter.Filter([]DataPoint{
+		{Timestamp: start.Add(10 * time.Second), LAC: 100},
+		{Timestamp: start.Add(10 * time.Second), LAC: 200},
+	})
+	if len(held) != 0 {
+		t.Fatalf("the data points without position should be held back, got %v", held)
+	}
+
+	flushed := Flush(filter)
+	if len(flushed) != 1 || flushed[0].LAC != 100 || flushed[0].GPSIssue != ZeroPosition {
+		t.Errorf("expected the flagged data point of LAC 100, got %v", flushed)
+	}
+	if len(Flush(filter)) != 0 {
+		t.Errorf("the data points should only be flushed once")
+	}
+}
@@ -1,6 +1,10 @@
 package data
 
-import "image/color"
+import (
+	"fmt"
+	"image/color"
+	"strings"
+)
 
 var (
 	NoGANColor     = color.RGBA{R: 0, G: 0, B: 0, A: 255}
@@ -13,18 +17,126 @@ var (
 	GAN4Color      = color.RGBA{R: 0, G: 100, B: 0, A: 255}
 )
 
-func GANToColor(gan int) color.Color {
-	colors := []color.Color{
-		GANMinus2Color,
-		GANMinus1Color,
-		GAN0Color,
-		GAN1Color,
-		GAN2Color,
-		GAN3Color,
-		GAN4Color,
+// ColorScheme defines the colors that represent the signal quality on a map. A scheme either uses one color per
+// GAN level, or a continuous gradient over the RSSI range of the GAN profile.
+type ColorScheme struct {
+	Name        string
+	Description string
+	// Colors contains the colors of the GAN levels -2 to 4, or the stops of the gradient from low to high RSSI.
+	Colors   []color.RGBA
+	NoSignal color.RGBA
+	Gradient bool
+}
+
+var DefaultColorScheme = ColorScheme{
+	Name:        "default",
+	Description: "red to green, one color per GAN level",
+	Colors:      []color.RGBA{GANMinus2Color, GANMinus1Color, GAN0Color, GAN1Color, GAN2Color, GAN3Color, GAN4Color},
+	NoSignal:    NoGANColor,
+}
+
+// ColorSchemes contains all available color schemes. The colorblind scheme uses the RdYlBu palette of ColorBrewer,
+// the viridis scheme the viridis palette of matplotlib. Both are readable with color vision deficiencies.
+var ColorSchemes = []ColorScheme{
+	DefaultColorScheme,
+	{
+		Name:        "colorblind",
+		Description: "red to blue, one color per GAN level, safe for color vision deficiencies",
+		Colors: []color.RGBA{
+			{R: 0xd7, G: 0x30, B: 0x27, A: 255},
+			{R: 0xf4, G: 0x6d, B: 0x43, A: 255},
+			{R: 0xfd, G: 0xae, B: 0x61, A: 255},
+			{R: 0xfe, G: 0xe0, B: 0x90, A: 255},
+			{R: 0xab, G: 0xd9, B: 0xe9, A: 255},
+			{R: 0x74, G: 0xad, B: 0xd1, A: 255},
+			{R: 0x45, G: 0x75, B: 0xb4, A: 255},
+		},
+		NoSignal: color.RGBA{R: 0x40, G: 0x40, B: 0x40, A: 255},
+	},
+	{
+		Name:        "rssi",
+		Description: "red to green, continuous gradient over the RSSI",
+		Colors:      []color.RGBA{GANMinus2Color, GAN0Color, GAN1Color, GAN4Color},
+		NoSignal:    NoGANColor,
+		Gradient:    true,
+	},
+	{
+		Name:        "viridis",
+		Description: "purple to yellow, continuous gradient over the RSSI, safe for color vision deficiencies",
+		Colors: []color.RGBA{
+			{R: 0x44, G: 0x01, B: 0x54, A: 255},
+			{R: 0x3b, G: 0x52, B: 0x8b, A: 255},
+			{R: 0x21, G: 0x91, B: 0x8c, A: 255},
+			{R: 0x5e, G: 0xc9, B: 0x62, A: 255},
+			{R: 0xfd, G: 0xe7, B: 0x25, A: 255},
+		},
+		NoSignal: color.RGBA{R: 0x80, G: 0x80, B: 0x80, A: 255},
+		Gradient: true,
+	},
+}
+
+func FindColorScheme(name string) (ColorScheme, error) {
+	for _, scheme := range ColorSchemes {
+		if strings.EqualFold(scheme.Name, name) {
+			return scheme, nil
+		}
 	}
+	return ColorScheme{}, fmt.Errorf("unknown color scheme: %s", name)
+}
+
+// GANColor returns the color of the given GAN level. Gradient schemes return the color at the threshold of the level.
+func (s ColorScheme) GANColor(gan int) color.Color {
 	if gan <= NoGAN || gan > 4 {
-		return NoGANColor
+		return s.NoSignal
 	}
-	return colors[gan+2]
+	if s.Gradient {
+		return s.gradientColor(float64(gan+2) / 6)
+	}
+	return s.Colors[gan+2]
+}
+
+// RSSIColor returns the color of the given RSSI. Gradient schemes interpolate over the thresholds of the given GAN
+// profile, extended by one level below the lowest threshold to distinguish GAN -2 from GAN -1. Other schemes use the
+// color of the GAN level.
+func (s ColorScheme) RSSIColor(profile GANProfile, rssi int) color.Color {
+	if rssi == NoSignal {
+		return s.NoSignal
+	}
+	if !s.Gradient {
+		return s.GANColor(profile.RSSIToGAN(rssi))
+	}
+	low := 2*profile.Thresholds[0] - profile.Thresholds[1]
+	high := profile.Thresholds[len(profile.Thresholds)-1]
+	return s.gradientColor(float64(rssi-low) / float64(high-low))
+}
+
+// gradientColor returns the color at the given position (0..1) of the gradient.
+func (s ColorScheme) gradientColor(position float64) color.Color {
+	position = min(max(position, 0), 1)
+	segments := len(s.Colors) - 1
+	segment := min(int(position*float64(segments)), segments-1)
+	fraction := position*float64(segments) - float64(segment)
+
+	from := s.Colors[segment]
+	to := s.Colors[segment+1]
+	interpolate := func(a, b uint8) uint8 {
+		return uint8(float64(a) + (float64(b)-float64(a))*fraction + 0.5)
+	}
+	return color.RGBA{
+		R: interpolate(from.R, to.R),
+		G: interpolate(from.G, to.G),
+		B: interpolate(from.B, to.B),
+		A: 255,
+	}
+}
+
+// GANToColor returns the color of the given GAN level in the default color scheme.
+func GANToColor(gan int) color.Color {
+	return DefaultColorScheme.GANColor(gan)
+}
+
+// ColorToHex returns the given color in the form #rrggbb.
+func ColorToHex(c color.Color) string {
+	rgba := color.RGBAModel.Convert(c).(color.RGBA)
+	return fmt.Sprintf("#%02x%02x%02x", rgba.R, rgba.G, rgba.B)
 }
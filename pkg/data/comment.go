@@ -0,0 +1,15 @@
+package data
+
+import (
+	"fmt"
+	"time"
+)
+
+// OutageComment returns a comment line for trace files that records a time window without connection to the radio.
+// The device name is optional.
+func OutageComment(device string, start, end time.Time) string {
+	if device != "" {
+		device = " of " + device
+	}
+	return fmt.Sprintf("# outage%s from %s to %s", device, start.UTC().Format(time.RFC3339), end.UTC().Format(time.RFC3339))
+}
@@ -0,0 +1,111 @@
+package data
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	GzipExtension = ".gz"
+	ZstdExtension = ".zst"
+)
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// SplitFilename splits the given filename into the base name and the extension. The extension includes the
+// extension of a compressed file, e.g. trace.csv.gz is split into trace and .csv.gz.
+func SplitFilename(filename string) (string, string) {
+	ext := filepath.Ext(filename)
+	switch strings.ToLower(ext) {
+	case GzipExtension, ZstdExtension:
+		ext = filepath.Ext(filename[:len(filename)-len(ext)]) + ext
+	}
+	return filename[:len(filename)-len(ext)], ext
+}
+
+// FileFormat returns the format of the given file in lower case, without the extension of a compressed file,
+// e.g. csv for trace.csv.gz.
+func FileFormat(filename string) string {
+	_, ext := SplitFilename(filename)
+	ext = strings.ToLower(ext)
+	ext = strings.TrimSuffix(ext, GzipExtension)
+	ext = strings.TrimSuffix(ext, ZstdExtension)
+	return strings.TrimPrefix(ext, ".")
+}
+
+// CreateFile creates the given file. If the file has the extension .gz or .zst, everything written to the file is
+// compressed with gzip or zstd.
+func CreateFile(filename string) (io.WriteCloser, error) {
+	file, err := os.Create(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var compressor io.WriteCloser
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case GzipExtension:
+		compressor = gzip.NewWriter(file)
+	case ZstdExtension:
+		compressor, err = zstd.NewWriter(file)
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("cannot create zstd compressor: %w", err)
+		}
+	default:
+		return file, nil
+	}
+
+	return &compressedFile{WriteCloser: compressor, file: file}, nil
+}
+
+type compressedFile struct {
+	io.WriteCloser
+	file *os.File
+}
+
+func (f *compressedFile) Close() error {
+	err := f.WriteCloser.Close()
+	fileErr := f.file.Close()
+	if err != nil {
+		return err
+	}
+	return fileErr
+}
+
+// Decompress detects gzip or zstd compressed content and decompresses it. Uncompressed content is passed through.
+// The returned reader must be closed, this does not close the given reader.
+func Decompress(in io.Reader) (io.ReadCloser, error) {
+	buffered := bufio.NewReader(in)
+	magic, err := buffered.Peek(len(zstdMagic))
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	switch {
+	case bytes.HasPrefix(magic, gzipMagic):
+		result, err := gzip.NewReader(buffered)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read gzip compressed data: %w", err)
+		}
+		return result, nil
+	case bytes.HasPrefix(magic, zstdMagic):
+		decoder, err := zstd.NewReader(buffered)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read zstd compressed data: %w", err)
+		}
+		return decoder.IOReadCloser(), nil
+	default:
+		return io.NopCloser(buffered), nil
+	}
+}
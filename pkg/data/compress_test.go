@@ -0,0 +1,146 @@
+package data
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+)
+
+const compressTestContent = "ts,lat,lon,lac,rssi\n2026-01-02T10:00:00Z,52.000000,13.000000,100,-80\n"
+
+func TestDecompress(t *testing.T) {
+	tt := []struct {
+		name     string
+		input    func(t *testing.T) []byte
+		expected string
+	}{
+		{
+			name: "gzip",
+			input: func(t *testing.T) []byte {
+				buffer := &bytes.Buffer{}
+				w := gzip.NewWriter(buffer)
+				w.Write([]byte(compressTestContent))
+				w.Close()
+				return buffer.Bytes()
+			},
+			expected: compressTestContent,
+		},
+		{
+			name: "zstd",
+			input: func(t *testing.T) []byte {
+				w, err := zstd.NewWriter(nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer w.Close()
+				return w.EncodeAll([]byte(compressTestContent), nil)
+			},
+			expected: compressTestContent,
+		},
+		{
+			name:     "plain",
+			input:    func(*testing.T) []byte { return []byte(compressTestContent) },
+			expected: compressTestContent,
+		},
+		{
+			name:     "plain, shorter than the magic bytes",
+			input:    func(*testing.T) []byte { return []byte{0x1f} },
+			expected: "\x1f",
+		},
+		{
+			name:     "empty",
+			input:    func(*testing.T) []byte { return nil },
+			expected: "",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			reader, err := Decompress(bytes.NewReader(tc.input(t)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer reader.Close()
+			actual, err := io.ReadAll(reader)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(actual) != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, string(actual))
+			}
+		})
+	}
+}
+
+func TestCreateFileCompressesByExtension(t *testing.T) {
+	tt := []struct {
+		filename string
+		magic    []byte
+	}{
+		{filename: "trace.csv.gz", magic: gzipMagic},
+		{filename: "trace.json.zst", magic: zstdMagic},
+		{filename: "trace.csv", magic: []byte("ts,")},
+	}
+	for _, tc := range tt {
+		t.Run(tc.filename, func(t *testing.T) {
+			filename := filepath.Join(t.TempDir(), tc.filename)
+			file, err := CreateFile(filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = io.WriteString(file, compressTestContent)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = file.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			written, err := os.ReadFile(filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.HasPrefix(written, tc.magic) {
+				t.Errorf("expected the file to start with %x, got %x", tc.magic, written[:min(len(written), 4)])
+			}
+			dataPoints, _, err := ReadDataPoints(bytes.NewReader(written))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(dataPoints) != 1 {
+				t.Errorf("expected 1 data point, got %d", len(dataPoints))
+			}
+		})
+	}
+}
+
+func TestSplitFilename(t *testing.T) {
+	tt := []struct {
+		filename string
+		base     string
+		ext      string
+		format   string
+	}{
+		{filename: "trace.csv", base: "trace", ext: ".csv", format: "csv"},
+		{filename: "trace.csv.gz", base: "trace", ext: ".csv.gz", format: "csv"},
+		{filename: "drive/trace.JSON.ZST", base: "drive/trace", ext: ".JSON.ZST", format: "json"},
+		{filename: "trace", base: "trace", ext: "", format: ""},
+	}
+	for _, tc := range tt {
+		t.Run(tc.filename, func(t *testing.T) {
+			base, ext := SplitFilename(tc.filename)
+			if base != tc.base || ext != tc.ext {
+				t.Errorf("expected %q and %q, got %q and %q", tc.base, tc.ext, base, ext)
+			}
+			format := FileFormat(tc.filename)
+			if format != tc.format {
+				t.Errorf("expected format %q, got %q", tc.format, format)
+			}
+		})
+	}
+}
@@ -3,13 +3,46 @@ package data
 import (
 	"encoding/csv"
 	"fmt"
+	"io"
 	"strconv"
 	"strings"
 	"time"
 )
 
+// CSVVersion is the version of the CSV format that is written by DataPointToCSV. Version 1 is the legacy format
+// without header line.
+const CSVVersion = 2
+
+// CSVColumns are the names of the columns written by DataPointToCSV, in this order.
+var CSVColumns = []string{"ts", "lat", "lon", "sats", "lac", "carrier", "rssi", "cx", "serving", "device", "alt", "speed", "heading", "fix", "hdop"}
+
+// requiredCSVColumns must be present in every CSV file, all other columns are optional.
+var requiredCSVColumns = []string{"ts", "lat", "lon", "lac", "rssi"}
+
+// LegacyCSVSchema describes the headerless CSV format, where the columns are identified by their position.
+var LegacyCSVSchema = CSVSchema{columns: csvColumnIndices(CSVColumns), legacy: true}
+
+// WriteCSVHeader writes the metadata, the version comment, and the header line that describe the CSV format of
+// DataPointToCSV.
+func WriteCSVHeader(out io.Writer, metadata Metadata) error {
+	_, err := fmt.Fprintf(out, "# tetra-mess CSV version %d\n", CSVVersion)
+	if err != nil {
+		return err
+	}
+	if !metadata.IsZero() {
+		_, err = fmt.Fprintln(out, MetadataToCSV(metadata))
+		if err != nil {
+			return err
+		}
+	}
+	_, err = fmt.Fprintln(out, strings.Join(CSVColumns, ","))
+	return err
+}
+
+// DataPointToCSV formats the data point as CSV line with the columns in CSVColumns. The carrier is written in
+// hexadecimal with 0x prefix, so that spreadsheets do not read it as decimal number.
 func DataPointToCSV(dataPoint DataPoint) string {
-	return fmt.Sprintf("%s,%f,%f,%d,%d,%x,%d,%d",
+	return fmt.Sprintf("%s,%f,%f,%d,%d,0x%x,%d,%d,%t,%s,%.1f,%.2f,%.1f,%s,%.1f",
 		dataPoint.Timestamp.Format(time.RFC3339),
 		dataPoint.Latitude,
 		dataPoint.Longitude,
@@ -17,55 +50,160 @@ func DataPointToCSV(dataPoint DataPoint) string {
 		dataPoint.LAC,
 		dataPoint.Carrier,
 		dataPoint.RSSI,
-		dataPoint.Cx)
+		dataPoint.Cx,
+		dataPoint.Serving,
+		dataPoint.Device,
+		dataPoint.Altitude,
+		dataPoint.Speed,
+		dataPoint.Heading,
+		dataPoint.FixType,
+		dataPoint.HDOP)
 }
 
 func IsCSVLine(line string) bool {
 	return strings.Contains(line, ",") && !strings.Contains(line, "{") && !strings.Contains(line, "}") && !strings.Contains(line, "\"") && !strings.Contains(line, "'")
 }
 
+// IsCSVHeader returns true if the given line is a CSV header line, i.e. it contains the columns ts, lat, and lon.
+func IsCSVHeader(line string) bool {
+	if !IsCSVLine(line) {
+		return false
+	}
+	columns := make(map[string]bool)
+	for _, column := range strings.Split(line, ",") {
+		columns[strings.ToLower(strings.TrimSpace(column))] = true
+	}
+	return columns["ts"] && columns["lat"] && columns["lon"]
+}
+
+// CSVSchema maps the column names of a CSV file to the column indices.
+type CSVSchema struct {
+	columns map[string]int
+	count   int
+	legacy  bool
+}
+
+// ParseCSVHeader parses a header line into a CSVSchema. Unknown columns are ignored.
+func ParseCSVHeader(line string) (CSVSchema, error) {
+	fields, err := readCSVFields(line)
+	if err != nil {
+		return CSVSchema{}, err
+	}
+	for i := range fields {
+		fields[i] = strings.ToLower(strings.TrimSpace(fields[i]))
+	}
+
+	result := CSVSchema{
+		columns: csvColumnIndices(fields),
+		count:   len(fields),
+	}
+	for _, column := range requiredCSVColumns {
+		if _, ok := result.columns[column]; !ok {
+			return CSVSchema{}, fmt.Errorf("missing required column %s in CSV header", column)
+		}
+	}
+	return result, nil
+}
+
+func csvColumnIndices(columns []string) map[string]int {
+	result := make(map[string]int, len(columns))
+	for i, column := range columns {
+		if _, ok := result[column]; !ok {
+			result[column] = i
+		}
+	}
+	return result
+}
+
+// ParseCSVLine parses a line in the legacy headerless CSV format.
 func ParseCSVLine(line string) (DataPoint, error) {
-	reader := csv.NewReader(strings.NewReader(line))
-	fields, err := reader.Read()
+	return LegacyCSVSchema.ParseLine(line)
+}
+
+// ParseLine parses a CSV line that follows this schema. The carrier is always hexadecimal, with or without 0x prefix,
+// as tetra-mess never wrote it in decimal.
+func (s CSVSchema) ParseLine(line string) (DataPoint, error) {
+	fields, err := readCSVFields(line)
 	if err != nil {
-		return DataPoint{}, fmt.Errorf("error reading CSV line: %w", err)
+		return DataPoint{}, err
 	}
-	if len(fields) != 8 {
-		return DataPoint{}, fmt.Errorf("expected 8 fields in CSV line, got %d", len(fields))
+	switch {
+	case s.legacy && (len(fields) < 8 || len(fields) > len(CSVColumns)):
+		return DataPoint{}, fmt.Errorf("expected 8 to %d fields in CSV line, got %d", len(CSVColumns), len(fields))
+	case !s.legacy && len(fields) != s.count:
+		return DataPoint{}, fmt.Errorf("expected %d fields in CSV line, got %d", s.count, len(fields))
+	}
+
+	field := func(column string) string {
+		i, ok := s.columns[column]
+		if !ok || i >= len(fields) {
+			return ""
+		}
+		return strings.TrimSpace(fields[i])
 	}
 
-	timestamp, err := time.Parse(time.RFC3339, fields[0])
+	timestamp, err := time.Parse(time.RFC3339, field("ts"))
 	if err != nil {
 		return DataPoint{}, fmt.Errorf("error parsing timestamp: %w", err)
 	}
-	lat, err := strconv.ParseFloat(fields[1], 64)
+	lat, err := strconv.ParseFloat(field("lat"), 64)
 	if err != nil {
 		return DataPoint{}, fmt.Errorf("error parsing latitude: %w", err)
 	}
-	lon, err := strconv.ParseFloat(fields[2], 64)
+	lon, err := strconv.ParseFloat(field("lon"), 64)
 	if err != nil {
 		return DataPoint{}, fmt.Errorf("error parsing longitude: %w", err)
 	}
-	sats, err := strconv.Atoi(fields[3])
+	sats, err := parseOptionalInt(field("sats"))
 	if err != nil {
 		return DataPoint{}, fmt.Errorf("error parsing satellites: %w", err)
 	}
-	lac, err := ParseDecOrHex(fields[4])
+	lac, err := ParseDecOrHex(field("lac"))
 	if err != nil {
 		return DataPoint{}, fmt.Errorf("error parsing LAC: %w", err)
 	}
-	carrier, err := ParseDecOrHex(fields[5])
-	if err != nil {
-		return DataPoint{}, fmt.Errorf("error parsing carrier: %w", err)
+	var carrier uint32
+	if field("carrier") != "" {
+		carrier, err = ParseHex(field("carrier"))
+		if err != nil {
+			return DataPoint{}, fmt.Errorf("error parsing carrier: %w", err)
+		}
 	}
-	rssi, err := strconv.Atoi(fields[6])
+	rssi, err := strconv.Atoi(field("rssi"))
 	if err != nil {
 		return DataPoint{}, fmt.Errorf("error parsing RSSI: %w", err)
 	}
-	cx, err := strconv.Atoi(fields[7])
+	cx, err := parseOptionalInt(field("cx"))
 	if err != nil {
 		return DataPoint{}, fmt.Errorf("error parsing Cx: %w", err)
 	}
+	var serving bool
+	if field("serving") != "" {
+		serving, err = strconv.ParseBool(field("serving"))
+		if err != nil {
+			return DataPoint{}, fmt.Errorf("error parsing serving flag: %w", err)
+		}
+	}
+	altitude, err := parseOptionalFloat(field("alt"))
+	if err != nil {
+		return DataPoint{}, fmt.Errorf("error parsing altitude: %w", err)
+	}
+	speed, err := parseOptionalFloat(field("speed"))
+	if err != nil {
+		return DataPoint{}, fmt.Errorf("error parsing speed: %w", err)
+	}
+	heading, err := parseOptionalFloat(field("heading"))
+	if err != nil {
+		return DataPoint{}, fmt.Errorf("error parsing heading: %w", err)
+	}
+	fixType, err := ParseFixType(field("fix"))
+	if err != nil {
+		return DataPoint{}, err
+	}
+	hdop, err := parseOptionalFloat(field("hdop"))
+	if err != nil {
+		return DataPoint{}, fmt.Errorf("error parsing HDOP: %w", err)
+	}
 
 	return DataPoint{
 		Timestamp:  timestamp,
@@ -76,5 +214,37 @@ func ParseCSVLine(line string) (DataPoint, error) {
 		Carrier:    carrier,
 		RSSI:       rssi,
 		Cx:         cx,
+		Serving:    serving,
+		Device:     field("device"),
+		Altitude:   altitude,
+		Speed:      speed,
+		Heading:    heading,
+		FixType:    fixType,
+		HDOP:       hdop,
 	}, nil
 }
+
+func readCSVFields(line string) ([]string, error) {
+	reader := csv.NewReader(strings.NewReader(line))
+	fields, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("error reading CSV line: %w", err)
+	}
+	return fields, nil
+}
+
+// parseOptionalInt parses the given value. An empty value is 0.
+func parseOptionalInt(value string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+	return strconv.Atoi(value)
+}
+
+// parseOptionalFloat parses the given value. An empty value is 0.
+func parseOptionalFloat(value string) (float64, error) {
+	if value == "" {
+		return 0, nil
+	}
+	return strconv.ParseFloat(value, 64)
+}
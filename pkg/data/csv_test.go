@@ -0,0 +1,211 @@
+package data
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCSVRoundTrip(t *testing.T) {
+	dataPoints := []DataPoint{
+		{
+			Latitude:   52.349885,
+			Longitude:  13.377718,
+			Satellites: 7,
+			Timestamp:  time.Date(2026, 10, 17, 8, 15, 2, 0, time.UTC),
+			LAC:        12345,
+			Carrier:    0xcaffe,
+			RSSI:       -61,
+			Cx:         43,
+			Serving:    true,
+			Device:     "roof",
+			Altitude:   35.2,
+			Speed:      13.89,
+			Heading:    271.5,
+			FixType:    Fix3D,
+			HDOP:       0.9,
+		},
+		{
+			Timestamp: time.Date(2026, 10, 17, 8, 15, 2, 0, time.UTC),
+			LAC:       12346,
+			Carrier:   0xcaffd,
+			RSSI:      NoSignal,
+		},
+		{
+			Timestamp: time.Date(2026, 10, 17, 8, 15, 3, 0, time.UTC),
+			LAC:       12346,
+			Carrier:   0x1234,
+			RSSI:      -95,
+		},
+	}
+	metadata := Metadata{Version: "test", Vehicle: "ELW 1", Radios: []RadioInfo{{Device: "roof", Model: "MTM5400"}}}
+
+	out := &bytes.Buffer{}
+	err := WriteCSVHeader(out, metadata)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, dataPoint := range dataPoints {
+		out.WriteString(DataPointToCSV(dataPoint) + "\n")
+	}
+	if !strings.HasPrefix(out.String(), "# tetra-mess CSV version 2\n") {
+		t.Errorf("missing version comment:\n%s", out.String())
+	}
+
+	actual, lineErrs, err := ReadDataPoints(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lineErrs) > 0 {
+		t.Fatalf("unexpected line errors: %v", lineErrs)
+	}
+	assertDataPoints(t, dataPoints, actual)
+}
+
+func TestParseLegacyCSVLine(t *testing.T) {
+	timestamp := time.Date(2026, 1, 2, 10, 0, 0, 0, time.UTC)
+	tt := []struct {
+		name     string
+		line     string
+		expected DataPoint
+		invalid  bool
+	}{
+		{
+			name:     "8 columns",
+			line:     "2026-01-02T10:00:00Z,52.000000,13.000000,7,100,3a0,-80,5",
+			expected: DataPoint{Latitude: 52, Longitude: 13, Satellites: 7, Timestamp: timestamp, LAC: 100, Carrier: 0x3a0, RSSI: -80, Cx: 5},
+		},
+		{
+			name:     "9 columns with serving flag",
+			line:     "2026-01-02T10:00:00Z,52.000000,13.000000,7,100,3a0,-80,5,true",
+			expected: DataPoint{Latitude: 52, Longitude: 13, Satellites: 7, Timestamp: timestamp, LAC: 100, Carrier: 0x3a0, RSSI: -80, Cx: 5, Serving: true},
+		},
+		{
+			name:     "no signal",
+			line:     "2026-01-02T10:00:00Z,0.000000,0.000000,0,100,3a0,99,0,false",
+			expected: DataPoint{Timestamp: timestamp, LAC: 100, Carrier: 0x3a0, RSSI: NoSignal},
+		},
+		{
+			name:     "carrier with digits only",
+			line:     "2026-01-02T10:00:00Z,52.000000,13.000000,7,100,1234,-80,5",
+			expected: DataPoint{Latitude: 52, Longitude: 13, Satellites: 7, Timestamp: timestamp, LAC: 100, Carrier: 0x1234, RSSI: -80, Cx: 5},
+		},
+		{
+			name:    "too few columns",
+			line:    "2026-01-02T10:00:00Z,52.000000,13.000000,7,100,3a0,-80",
+			invalid: true,
+		},
+		{
+			name:    "invalid RSSI",
+			line:    "2026-01-02T10:00:00Z,52.000000,13.000000,7,100,3a0,strong,5",
+			invalid: true,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			actual, err := ParseCSVLine(tc.line)
+			if tc.invalid {
+				if err == nil {
+					t.Errorf("expected an error, got %v", actual)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertDataPoints(t, []DataPoint{tc.expected}, []DataPoint{actual})
+		})
+	}
+}
+
+func TestReadCSVWithHeader(t *testing.T) {
+	timestamp := time.Date(2026, 1, 2, 10, 0, 0, 0, time.UTC)
+	tt := []struct {
+		name     string
+		content  string
+		expected []DataPoint
+		invalid  bool
+	}{
+		{
+			name: "reordered columns",
+			content: "rssi,lac,ts,lon,lat,serving\n" +
+				"-80,100,2026-01-02T10:00:00Z,13.000000,52.000000,true\n",
+			expected: []DataPoint{{Latitude: 52, Longitude: 13, Timestamp: timestamp, LAC: 100, RSSI: -80, Serving: true}},
+		},
+		{
+			name: "unknown columns are ignored",
+			content: "ts,note,lat,lon,lac,carrier,rssi,speed\n" +
+				"2026-01-02T10:00:00Z,first drive,52.000000,13.000000,100,3a0,-80,12.50\n",
+			expected: []DataPoint{{Latitude: 52, Longitude: 13, Timestamp: timestamp, LAC: 100, Carrier: 0x3a0, RSSI: -80, Speed: 12.5}},
+		},
+		{
+			name: "header after legacy lines",
+			content: "2026-01-02T10:00:00Z,52.000000,13.000000,7,100,3a0,-80,5\n" +
+				"lac,rssi,ts,lat,lon\n" +
+				"101,-90,2026-01-02T10:00:00Z,52.000000,13.000000\n",
+			expected: []DataPoint{
+				{Latitude: 52, Longitude: 13, Satellites: 7, Timestamp: timestamp, LAC: 100, Carrier: 0x3a0, RSSI: -80, Cx: 5},
+				{Latitude: 52, Longitude: 13, Timestamp: timestamp, LAC: 101, RSSI: -90},
+			},
+		},
+		{
+			name:    "missing required column",
+			content: "ts,lat,lon,lac\n2026-01-02T10:00:00Z,52.000000,13.000000,100\n",
+			invalid: true,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			actual, lineErrs, err := ReadDataPoints(strings.NewReader(tc.content))
+			if tc.invalid {
+				if err == nil {
+					t.Errorf("expected an error, got %v", actual)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(lineErrs) > 0 {
+				t.Fatalf("unexpected line errors: %v", lineErrs)
+			}
+			assertDataPoints(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestReadCSVReportsInvalidLines(t *testing.T) {
+	content := "ts,lat,lon,lac,rssi\n" +
+		"2026-01-02T10:00:00Z,52.000000,13.000000,100,-80\n" +
+		"2026-01-02T10:00:05Z,52.000000,13.000000,100\n" +
+		"2026-01-02T10:00:10Z,52.000000,13.000000,100,-82\n"
+
+	actual, lineErrs, err := ReadDataPoints(strings.NewReader(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(actual) != 2 {
+		t.Errorf("expected 2 data points, got %d", len(actual))
+	}
+	if len(lineErrs) != 1 || lineErrs[0].Line != 3 {
+		t.Errorf("expected a line error in line 3, got %v", lineErrs)
+	}
+}
+
+func assertDataPoints(t *testing.T, expected []DataPoint, actual []DataPoint) {
+	t.Helper()
+	if len(actual) != len(expected) {
+		t.Fatalf("expected %d data points, got %d: %v", len(expected), len(actual), actual)
+	}
+	for i := range expected {
+		e, a := expected[i], actual[i]
+		if !a.Timestamp.Equal(e.Timestamp) {
+			t.Errorf("%d: expected timestamp %v, got %v", i, e.Timestamp, a.Timestamp)
+		}
+		e.Timestamp, a.Timestamp = time.Time{}, time.Time{}
+		if a != e {
+			t.Errorf("%d: expected\n%+v\ngot\n%+v", i, e, a)
+		}
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"crypto/md5"
 	"encoding/hex"
 	"fmt"
+	"math"
+	"strings"
 	"time"
 
 	"github.com/im7mortal/UTM"
@@ -15,22 +17,164 @@ const NoGAN = -3
 var NoPosition = Position{}
 var ZeroDataPoint = DataPoint{}
 
+// FixType describes the kind of the GPS fix. The values correspond to the NMEA GSA sentence and the gpsd mode.
+type FixType int
+
+const (
+	UnknownFix FixType = 0
+	NoFix      FixType = 1
+	Fix2D      FixType = 2
+	Fix3D      FixType = 3
+)
+
+func ParseFixType(s string) (FixType, error) {
+	switch strings.ToLower(s) {
+	case "", "unknown":
+		return UnknownFix, nil
+	case "none":
+		return NoFix, nil
+	case "2d":
+		return Fix2D, nil
+	case "3d":
+		return Fix3D, nil
+	default:
+		return UnknownFix, fmt.Errorf("invalid fix type: %s", s)
+	}
+}
+
+func (t FixType) String() string {
+	switch t {
+	case NoFix:
+		return "none"
+	case Fix2D:
+		return "2d"
+	case Fix3D:
+		return "3d"
+	default:
+		return "unknown"
+	}
+}
+
+// Position is a GPS position. Altitude is given in metres, speed in m/s and heading in degrees from north.
+// A HDOP of 0 means that the HDOP is unknown.
 type Position struct {
 	Latitude   float64
 	Longitude  float64
 	Satellites int
 	Timestamp  time.Time
+	Altitude   float64
+	Speed      float64
+	Heading    float64
+	FixType    FixType
+	HDOP       float64
 }
 
 func (p Position) ToUTMField() UTMField {
 	return NewUTMField(p.Latitude, p.Longitude)
 }
 
+func (p Position) HasFix() bool {
+	return p.Satellites > 0
+}
+
+// DistanceTo returns the great-circle distance to the other position in metres.
+func (p Position) DistanceTo(other Position) float64 {
+	return Distance(p.Latitude, p.Longitude, other.Latitude, other.Longitude)
+}
+
+// SpeedTo returns the speed in m/s that is required to travel from this position to the other position.
+// The result is only valid if the timestamp of the other position is after this position's timestamp.
+func (p Position) SpeedTo(other Position) (float64, bool) {
+	duration := other.Timestamp.Sub(p.Timestamp).Seconds()
+	if duration <= 0 {
+		return 0, false
+	}
+	return p.DistanceTo(other) / duration, true
+}
+
+// HeadingTo returns the initial bearing from this position to the other position in degrees from north.
+func (p Position) HeadingTo(other Position) float64 {
+	return Heading(p.Latitude, p.Longitude, other.Latitude, other.Longitude)
+}
+
+// WithMotion fills in speed and heading from the previous fix if this position does not provide them.
+func (p Position) WithMotion(previous Position) Position {
+	if p.Speed != 0 || p.Heading != 0 || !p.HasFix() || !previous.HasFix() {
+		return p
+	}
+	speed, ok := previous.SpeedTo(p)
+	if !ok {
+		return p
+	}
+	p.Speed = speed
+	if speed > 0 {
+		p.Heading = previous.HeadingTo(p)
+	}
+	return p
+}
+
+// InterpolatePosition returns the position at the given time, interpolated linearly between the previous and the
+// next position. The quality of the result is the worse quality of both positions.
+func InterpolatePosition(previous, next Position, timestamp time.Time) Position {
+	gap := next.Timestamp.Sub(previous.Timestamp)
+	fraction := 0.0
+	if gap > 0 {
+		fraction = float64(timestamp.Sub(previous.Timestamp)) / float64(gap)
+	}
+	interpolate := func(a, b float64) float64 {
+		return a + (b-a)*fraction
+	}
+	result := Position{
+		Latitude:   interpolate(previous.Latitude, next.Latitude),
+		Longitude:  interpolate(previous.Longitude, next.Longitude),
+		Satellites: min(previous.Satellites, next.Satellites),
+		Timestamp:  timestamp,
+		Altitude:   interpolate(previous.Altitude, next.Altitude),
+		FixType:    min(previous.FixType, next.FixType),
+		HDOP:       max(previous.HDOP, next.HDOP),
+	}
+	speed, ok := previous.SpeedTo(next)
+	if ok {
+		result.Speed = speed
+	}
+	if speed > 0 {
+		result.Heading = previous.HeadingTo(next)
+	}
+	return result
+}
+
+const earthRadius = 6371000.0
+
+// Distance returns the great-circle distance between the two given coordinates in metres.
+func Distance(lat1, lon1, lat2, lon2 float64) float64 {
+	phi1 := lat1 * math.Pi / 180
+	phi2 := lat2 * math.Pi / 180
+	deltaPhi := (lat2 - lat1) * math.Pi / 180
+	deltaLambda := (lon2 - lon1) * math.Pi / 180
+
+	a := math.Sin(deltaPhi/2)*math.Sin(deltaPhi/2) + math.Cos(phi1)*math.Cos(phi2)*math.Sin(deltaLambda/2)*math.Sin(deltaLambda/2)
+	return 2 * earthRadius * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+}
+
+// Heading returns the initial bearing from the first to the second coordinate in degrees from north.
+func Heading(lat1, lon1, lat2, lon2 float64) float64 {
+	phi1 := lat1 * math.Pi / 180
+	phi2 := lat2 * math.Pi / 180
+	deltaLambda := (lon2 - lon1) * math.Pi / 180
+
+	y := math.Sin(deltaLambda) * math.Cos(phi2)
+	x := math.Cos(phi1)*math.Sin(phi2) - math.Sin(phi1)*math.Cos(phi2)*math.Cos(deltaLambda)
+	result := math.Atan2(y, x) * 180 / math.Pi
+	return math.Mod(result+360, 360)
+}
+
 type CellInfo struct {
 	LAC     uint32
 	Carrier uint32
 	RSSI    int
 	Cx      int
+	// Serving is set if the radio reported this cell as the cell it is registered to.
+	Serving bool
 }
 
 type DataPoint struct {
@@ -42,6 +186,56 @@ type DataPoint struct {
 	Carrier    uint32    `json:"carrier"`
 	RSSI       int       `json:"rssi"`
 	Cx         int       `json:"cx"`
+	Serving    bool      `json:"serving,omitempty"`
+	Device     string    `json:"device,omitempty"`
+	Altitude   float64   `json:"alt,omitempty"`
+	Speed      float64   `json:"speed,omitempty"`
+	Heading    float64   `json:"heading,omitempty"`
+	FixType    FixType   `json:"fix,omitempty"`
+	HDOP       float64   `json:"hdop,omitempty"`
+	GPSIssue   GPSIssue  `json:"gps_issue,omitempty"`
+}
+
+// WithPosition returns a copy of this data point at the given position.
+func (dp DataPoint) WithPosition(position Position) DataPoint {
+	result := NewDataPoint(position)
+	result.Timestamp = dp.Timestamp
+	result.LAC = dp.LAC
+	result.Carrier = dp.Carrier
+	result.RSSI = dp.RSSI
+	result.Cx = dp.Cx
+	result.Serving = dp.Serving
+	result.Device = dp.Device
+	return result
+}
+
+// NewDataPoint creates a data point at the given position.
+func NewDataPoint(position Position) DataPoint {
+	return DataPoint{
+		Latitude:   position.Latitude,
+		Longitude:  position.Longitude,
+		Satellites: position.Satellites,
+		Timestamp:  position.Timestamp,
+		Altitude:   position.Altitude,
+		Speed:      position.Speed,
+		Heading:    position.Heading,
+		FixType:    position.FixType,
+		HDOP:       position.HDOP,
+	}
+}
+
+func (dp DataPoint) Position() Position {
+	return Position{
+		Latitude:   dp.Latitude,
+		Longitude:  dp.Longitude,
+		Satellites: dp.Satellites,
+		Timestamp:  dp.Timestamp,
+		Altitude:   dp.Altitude,
+		Speed:      dp.Speed,
+		Heading:    dp.Heading,
+		FixType:    dp.FixType,
+		HDOP:       dp.HDOP,
+	}
 }
 
 func (dp DataPoint) IsZero() bool {
@@ -52,12 +246,20 @@ func (dp DataPoint) IsValid() bool {
 	return dp.Satellites > 0 && dp.RSSI != NoSignal
 }
 
+// HasValidPosition indicates if the data point was measured with a GPS fix at a position other than 0/0.
+func (dp DataPoint) HasValidPosition() bool {
+	return dp.Satellites > 0 && (dp.Latitude != 0 || dp.Longitude != 0)
+}
+
 func (dp DataPoint) IsUsable() bool {
 	return IsUsableRSSI(dp.RSSI)
 }
 
 func (dp DataPoint) MeasurementID() string {
 	data := fmt.Sprintf("%f-%f-%s", dp.Latitude, dp.Longitude, dp.Timestamp.Format(time.RFC3339))
+	if dp.Device != "" {
+		data += "-" + dp.Device
+	}
 	hash := md5.Sum([]byte(data))
 	return hex.EncodeToString(hash[:])
 }
@@ -114,27 +316,12 @@ func (f UTMField) Area() (minLat float64, minLon float64, maxLat float64, maxLon
 	return
 }
 
+// RSSIToGAN returns the GAN of the given RSSI according to the default GAN profile.
 func RSSIToGAN(rssi int) int {
-	switch {
-	case rssi == NoSignal:
-		return NoGAN
-	case rssi < -103:
-		return -2
-	case rssi < -97:
-		return -1
-	case rssi < -94:
-		return 0
-	case rssi < -88:
-		return 1
-	case rssi < -85:
-		return 2
-	case rssi < -79:
-		return 3
-	default:
-		return 4
-	}
+	return DefaultGANProfile.RSSIToGAN(rssi)
 }
 
+// IsUsableRSSI tells if a server with the given RSSI is usable according to the default GAN profile.
 func IsUsableRSSI(rssi int) bool {
-	return rssi >= -94
+	return DefaultGANProfile.IsUsableRSSI(rssi)
 }
@@ -0,0 +1,414 @@
+package data
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Predicate decides if a data point is kept. A predicate can be used as Filter.
+type Predicate func(DataPoint) bool
+
+func (p Predicate) Filter(dataPoints []DataPoint) []DataPoint {
+	result := make([]DataPoint, 0, len(dataPoints))
+	for _, dp := range dataPoints {
+		if p(dp) {
+			result = append(result, dp)
+		}
+	}
+	return result
+}
+
+// And keeps data points that fulfill all given predicates.
+func And(predicates ...Predicate) Predicate {
+	return func(dp DataPoint) bool {
+		for _, predicate := range predicates {
+			if !predicate(dp) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Or keeps data points that fulfill at least one of the given predicates.
+func Or(predicates ...Predicate) Predicate {
+	return func(dp DataPoint) bool {
+		for _, predicate := range predicates {
+			if predicate(dp) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Not keeps data points that do not fulfill the given predicate.
+func Not(predicate Predicate) Predicate {
+	return func(dp DataPoint) bool {
+		return !predicate(dp)
+	}
+}
+
+// expressionField is a field of a data point that can be compared in a filter expression. All values are mapped to
+// numbers, e.g. timestamps to seconds since the epoch.
+type expressionField struct {
+	value func(DataPoint) float64
+	parse func(string) (float64, error)
+	// cyclic fields wrap around, e.g. "time between 22:00 and 02:00" covers midnight
+	cyclic bool
+}
+
+var expressionFields = map[string]expressionField{
+	"ts":      {value: func(dp DataPoint) float64 { return unixSeconds(dp.Timestamp) }, parse: parseTimestampValue},
+	"time":    {value: func(dp DataPoint) float64 { return secondsOfDay(dp.Timestamp) }, parse: parseTimeOfDayValue, cyclic: true},
+	"lat":     {value: func(dp DataPoint) float64 { return dp.Latitude }, parse: parseFloatValue},
+	"lon":     {value: func(dp DataPoint) float64 { return dp.Longitude }, parse: parseFloatValue},
+	"sats":    {value: func(dp DataPoint) float64 { return float64(dp.Satellites) }, parse: parseFloatValue},
+	"lac":     {value: func(dp DataPoint) float64 { return float64(dp.LAC) }, parse: parseDecOrHexValue},
+	"carrier": {value: func(dp DataPoint) float64 { return float64(dp.Carrier) }, parse: parseDecOrHexValue},
+	"rssi":    {value: func(dp DataPoint) float64 { return float64(dp.RSSI) }, parse: parseFloatValue},
+	"cx":      {value: func(dp DataPoint) float64 { return float64(dp.Cx) }, parse: parseFloatValue},
+	"serving": {value: func(dp DataPoint) float64 { return boolToFloat(dp.Serving) }, parse: parseBoolValue},
+	"alt":     {value: func(dp DataPoint) float64 { return dp.Altitude }, parse: parseFloatValue},
+	"speed":   {value: func(dp DataPoint) float64 { return dp.Speed * 3.6 }, parse: parseFloatValue},
+	"heading": {value: func(dp DataPoint) float64 { return dp.Heading }, parse: parseFloatValue},
+	"fix":     {value: func(dp DataPoint) float64 { return float64(dp.FixType) }, parse: parseFixTypeValue},
+	"hdop":    {value: func(dp DataPoint) float64 { return dp.HDOP }, parse: parseFloatValue},
+}
+
+// ParseFilterExpression compiles the given filter expression into a predicate. An expression consists of
+// comparisons that are combined with and, or, not and parentheses, e.g.
+//
+//	lac = 1234 and rssi > -95 and time between 08:00 and 10:00
+//
+// A comparison has the form <field> <operator> <value> with one of the operators =, !=, <, <=, >, >=, or the form
+// <field> between <value> and <value>, which includes both values. The fields are named like the CSV columns:
+// ts, lat, lon, sats, lac, carrier, rssi, cx, serving, device, alt, speed (in km/h), heading, fix, and hdop.
+// Additionally, gan is the GAN of the RSSI according to the given GAN profile, and time is the local time of day
+// (15:04 or 15:04:05). Timestamps are given in RFC3339 format or as local date and time (2006-01-02T15:04 or
+// 2006-01-02). The device can only be compared with = and !=.
+func ParseFilterExpression(expression string, profile GANProfile) (Predicate, error) {
+	tokens, err := tokenizeExpression(expression)
+	if err != nil {
+		return nil, fmt.Errorf("invalid filter expression: %w", err)
+	}
+	p := &expressionParser{tokens: tokens, profile: profile}
+	result, err := p.parseOr()
+	if err == nil && !p.done() {
+		err = fmt.Errorf("unexpected %q", p.peek())
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid filter expression: %w", err)
+	}
+	return result, nil
+}
+
+const expressionOperatorChars = "=!<>"
+
+func tokenizeExpression(expression string) ([]string, error) {
+	var result []string
+	runes := []rune(expression)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(' || r == ')':
+			result = append(result, string(r))
+			i++
+		case strings.ContainsRune(expressionOperatorChars, r):
+			start := i
+			for i < len(runes) && strings.ContainsRune(expressionOperatorChars, runes[i]) {
+				i++
+			}
+			result = append(result, string(runes[start:i]))
+		case r == '"' || r == '\'':
+			end := i + 1
+			for end < len(runes) && runes[end] != r {
+				end++
+			}
+			if end == len(runes) {
+				return nil, fmt.Errorf("unterminated string")
+			}
+			result = append(result, string(runes[i:end+1]))
+			i = end + 1
+		default:
+			start := i
+			for i < len(runes) && !unicode.IsSpace(runes[i]) && !strings.ContainsRune(`()"'`+expressionOperatorChars, runes[i]) {
+				i++
+			}
+			result = append(result, string(runes[start:i]))
+		}
+	}
+	return result, nil
+}
+
+type expressionParser struct {
+	tokens  []string
+	pos     int
+	profile GANProfile
+}
+
+func (p *expressionParser) done() bool {
+	return p.pos >= len(p.tokens)
+}
+
+func (p *expressionParser) peek() string {
+	if p.done() {
+		return ""
+	}
+	return p.tokens[p.pos]
+}
+
+func (p *expressionParser) next() (string, error) {
+	if p.done() {
+		return "", fmt.Errorf("unexpected end of expression")
+	}
+	p.pos++
+	return p.tokens[p.pos-1], nil
+}
+
+func (p *expressionParser) acceptKeyword(keyword string) bool {
+	if strings.EqualFold(p.peek(), keyword) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *expressionParser) parseOr() (Predicate, error) {
+	predicates, err := p.parseList("or", p.parseAnd)
+	if err != nil {
+		return nil, err
+	}
+	if len(predicates) == 1 {
+		return predicates[0], nil
+	}
+	return Or(predicates...), nil
+}
+
+func (p *expressionParser) parseAnd() (Predicate, error) {
+	predicates, err := p.parseList("and", p.parseUnary)
+	if err != nil {
+		return nil, err
+	}
+	if len(predicates) == 1 {
+		return predicates[0], nil
+	}
+	return And(predicates...), nil
+}
+
+func (p *expressionParser) parseList(separator string, parseElement func() (Predicate, error)) ([]Predicate, error) {
+	var result []Predicate
+	for {
+		predicate, err := parseElement()
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, predicate)
+		if !p.acceptKeyword(separator) {
+			return result, nil
+		}
+	}
+}
+
+func (p *expressionParser) parseUnary() (Predicate, error) {
+	if p.acceptKeyword("not") {
+		predicate, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return Not(predicate), nil
+	}
+	if p.acceptKeyword("(") {
+		predicate, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.acceptKeyword(")") {
+			return nil, fmt.Errorf("missing )")
+		}
+		return predicate, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *expressionParser) parseComparison() (Predicate, error) {
+	name, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	name = strings.ToLower(name)
+
+	if p.acceptKeyword("between") {
+		from, err := p.next()
+		if err != nil {
+			return nil, err
+		}
+		if !p.acceptKeyword("and") {
+			return nil, fmt.Errorf("missing and after %s between %s", name, from)
+		}
+		to, err := p.next()
+		if err != nil {
+			return nil, err
+		}
+		return p.between(name, unquote(from), unquote(to))
+	}
+
+	operator, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	value, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	return p.compare(name, operator, unquote(value))
+}
+
+func (p *expressionParser) field(name string) (expressionField, error) {
+	if name == "gan" {
+		return expressionField{
+			value: func(dp DataPoint) float64 { return float64(p.profile.RSSIToGAN(dp.RSSI)) },
+			parse: parseFloatValue,
+		}, nil
+	}
+	field, ok := expressionFields[name]
+	if !ok {
+		return expressionField{}, fmt.Errorf("unknown field %q", name)
+	}
+	return field, nil
+}
+
+func (p *expressionParser) compare(name string, operator string, value string) (Predicate, error) {
+	if name == "device" {
+		return compareDevice(operator, value)
+	}
+	field, err := p.field(name)
+	if err != nil {
+		return nil, err
+	}
+	operand, err := field.parse(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid value for %s: %w", name, err)
+	}
+
+	get := field.value
+	switch operator {
+	case "=", "==":
+		return func(dp DataPoint) bool { return get(dp) == operand }, nil
+	case "!=":
+		return func(dp DataPoint) bool { return get(dp) != operand }, nil
+	case "<":
+		return func(dp DataPoint) bool { return get(dp) < operand }, nil
+	case "<=":
+		return func(dp DataPoint) bool { return get(dp) <= operand }, nil
+	case ">":
+		return func(dp DataPoint) bool { return get(dp) > operand }, nil
+	case ">=":
+		return func(dp DataPoint) bool { return get(dp) >= operand }, nil
+	default:
+		return nil, fmt.Errorf("unknown operator %q", operator)
+	}
+}
+
+func compareDevice(operator string, value string) (Predicate, error) {
+	switch operator {
+	case "=", "==":
+		return func(dp DataPoint) bool { return dp.Device == value }, nil
+	case "!=":
+		return func(dp DataPoint) bool { return dp.Device != value }, nil
+	default:
+		return nil, fmt.Errorf("device can only be compared with = or !=")
+	}
+}
+
+func (p *expressionParser) between(name string, from string, to string) (Predicate, error) {
+	lower, err := p.compare(name, ">=", from)
+	if err != nil {
+		return nil, err
+	}
+	upper, err := p.compare(name, "<=", to)
+	if err != nil {
+		return nil, err
+	}
+
+	field, _ := p.field(name)
+	if field.cyclic {
+		fromValue, _ := field.parse(from)
+		toValue, _ := field.parse(to)
+		if fromValue > toValue {
+			return Or(lower, upper), nil
+		}
+	}
+	return And(lower, upper), nil
+}
+
+func unquote(value string) string {
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		return value[1 : len(value)-1]
+	}
+	return value
+}
+
+func unixSeconds(t time.Time) float64 {
+	return float64(t.UnixNano()) / float64(time.Second)
+}
+
+func secondsOfDay(t time.Time) float64 {
+	local := t.Local()
+	return float64(local.Hour()*3600 + local.Minute()*60 + local.Second())
+}
+
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func parseFloatValue(s string) (float64, error) {
+	return strconv.ParseFloat(s, 64)
+}
+
+func parseDecOrHexValue(s string) (float64, error) {
+	value, err := ParseDecOrHex(s)
+	return float64(value), err
+}
+
+func parseBoolValue(s string) (float64, error) {
+	value, err := strconv.ParseBool(s)
+	return boolToFloat(value), err
+}
+
+func parseFixTypeValue(s string) (float64, error) {
+	value, err := ParseFixType(s)
+	return float64(value), err
+}
+
+func parseTimestampValue(s string) (float64, error) {
+	t, err := time.Parse(time.RFC3339, s)
+	if err == nil {
+		return unixSeconds(t), nil
+	}
+	for _, layout := range []string{"2006-01-02T15:04:05", "2006-01-02T15:04", "2006-01-02"} {
+		t, err := time.ParseInLocation(layout, s, time.Local)
+		if err == nil {
+			return unixSeconds(t), nil
+		}
+	}
+	return 0, fmt.Errorf("invalid timestamp: %s", s)
+}
+
+func parseTimeOfDayValue(s string) (float64, error) {
+	for _, layout := range []string{"15:04:05", "15:04"} {
+		t, err := time.Parse(layout, s)
+		if err == nil {
+			return float64(t.Hour()*3600 + t.Minute()*60 + t.Second()), nil
+		}
+	}
+	return 0, fmt.Errorf("invalid time of day: %s", s)
+}
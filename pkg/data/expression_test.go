@@ -0,0 +1,86 @@
+package data
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseFilterExpression(t *testing.T) {
+	at := func(hour, minute int) time.Time {
+		return time.Date(2026, 1, 2, hour, minute, 0, 0, time.Local)
+	}
+	tt := []struct {
+		name       string
+		expression string
+		dataPoint  DataPoint
+		expected   bool
+	}{
+		{name: "equal", expression: "lac = 1234", dataPoint: DataPoint{LAC: 1234}, expected: true},
+		{name: "not equal", expression: "lac != 1234", dataPoint: DataPoint{LAC: 1234}, expected: false},
+		{name: "hex value", expression: "carrier = 3a0", dataPoint: DataPoint{Carrier: 0x3a0}, expected: true},
+		{name: "hex value with prefix", expression: "carrier = 0x1234", dataPoint: DataPoint{Carrier: 0x1234}, expected: true},
+		{name: "negative value", expression: "rssi >= -95", dataPoint: DataPoint{RSSI: -95}, expected: true},
+		{name: "operator without spaces", expression: "rssi<-95", dataPoint: DataPoint{RSSI: -95}, expected: false},
+		{name: "and binds stronger than or", expression: "lac = 1 or lac = 2 and rssi > -90", dataPoint: DataPoint{LAC: 1, RSSI: -100}, expected: true},
+		{name: "and binds stronger than or, second operand", expression: "lac = 1 or lac = 2 and rssi > -90", dataPoint: DataPoint{LAC: 2, RSSI: -100}, expected: false},
+		{name: "parentheses", expression: "(lac = 1 or lac = 2) and rssi > -90", dataPoint: DataPoint{LAC: 1, RSSI: -100}, expected: false},
+		{name: "not binds stronger than and", expression: "not lac = 1 and rssi > -90", dataPoint: DataPoint{LAC: 2, RSSI: -80}, expected: true},
+		{name: "not with parentheses", expression: "not (lac = 1 or rssi > -90)", dataPoint: DataPoint{LAC: 2, RSSI: -80}, expected: false},
+		{name: "keywords are case insensitive", expression: "LAC = 1 AND NOT serving = true", dataPoint: DataPoint{LAC: 1}, expected: true},
+		{name: "between includes the lower bound", expression: "rssi between -95 and -85", dataPoint: DataPoint{RSSI: -95}, expected: true},
+		{name: "between includes the upper bound", expression: "rssi between -95 and -85", dataPoint: DataPoint{RSSI: -85}, expected: true},
+		{name: "outside of between", expression: "rssi between -95 and -85", dataPoint: DataPoint{RSSI: -84}, expected: false},
+		{name: "time between", expression: "time between 08:00 and 10:00", dataPoint: DataPoint{Timestamp: at(9, 30)}, expected: true},
+		{name: "time between wraps around midnight, before", expression: "time between 22:00 and 02:00", dataPoint: DataPoint{Timestamp: at(23, 0)}, expected: true},
+		{name: "time between wraps around midnight, after", expression: "time between 22:00 and 02:00", dataPoint: DataPoint{Timestamp: at(1, 30)}, expected: true},
+		{name: "time between wraps around midnight, outside", expression: "time between 22:00 and 02:00", dataPoint: DataPoint{Timestamp: at(12, 0)}, expected: false},
+		{name: "between and and", expression: "rssi between -95 and -85 and lac = 1", dataPoint: DataPoint{RSSI: -90, LAC: 2}, expected: false},
+		{name: "device", expression: "device = 'roof'", dataPoint: DataPoint{Device: "roof"}, expected: true},
+		{name: "speed in km/h", expression: "speed > 50", dataPoint: DataPoint{Speed: 15}, expected: true},
+		{name: "gan", expression: "gan >= 2", dataPoint: DataPoint{RSSI: -88}, expected: true},
+		{name: "fix type", expression: "fix >= 2d", dataPoint: DataPoint{FixType: Fix3D}, expected: true},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			predicate, err := ParseFilterExpression(tc.expression, DefaultGANProfile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			actual := predicate(tc.dataPoint)
+			if actual != tc.expected {
+				t.Errorf("expected %t, got %t", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestParseFilterExpressionInvalid(t *testing.T) {
+	tt := []string{
+		"",
+		"lac",
+		"lac =",
+		"lac = 1 and",
+		"or lac = 1",
+		"(lac = 1",
+		"lac = 1)",
+		"()",
+		"not",
+		"unknown = 1",
+		"lac = xyz",
+		"rssi => -95",
+		"rssi between -95",
+		"rssi between -95 or -85",
+		"time between 25:00 and 02:00",
+		"device < 'roof'",
+		"device = 'roof",
+		"lac = 1 lac = 2",
+	}
+	for _, expression := range tt {
+		t.Run(expression, func(t *testing.T) {
+			_, err := ParseFilterExpression(expression, DefaultGANProfile)
+			if err == nil {
+				t.Errorf("expected an error")
+			}
+		})
+	}
+}
@@ -12,6 +12,78 @@ func (f FilterFunc) Filter(dataPoints []DataPoint) []DataPoint {
 	return f(dataPoints)
 }
 
+// Flusher is implemented by filters that hold back data points, e.g. the Cleaner in interpolate mode.
+type Flusher interface {
+	// Flush returns the data points that are still held back at the end of the sequence.
+	Flush() []DataPoint
+}
+
+// Flush returns the data points that the given filter still holds back at the end of the sequence.
+func Flush(filter Filter) []DataPoint {
+	flusher, ok := filter.(Flusher)
+	if !ok {
+		return nil
+	}
+	return flusher.Flush()
+}
+
+// Filters applies all given filters in the given order. Flushing the result flushes all given filters, the data
+// points released by one filter are passed through the following filters.
+func Filters(filters ...Filter) Filter {
+	return filterChain(filters)
+}
+
+type filterChain []Filter
+
+func (c filterChain) Filter(dataPoints []DataPoint) []DataPoint {
+	for _, filter := range c {
+		dataPoints = filter.Filter(dataPoints)
+	}
+	return dataPoints
+}
+
+func (c filterChain) Flush() []DataPoint {
+	var result []DataPoint
+	for _, filter := range c {
+		if len(result) > 0 {
+			result = filter.Filter(result)
+		}
+		result = append(result, Flush(filter)...)
+	}
+	return result
+}
+
+func filterBy(keep func(DataPoint) bool) Filter {
+	return Predicate(keep)
+}
+
+// FilterByMaxHDOP drops all data points with a HDOP above the given maximum. Data points with unknown HDOP are kept.
+func FilterByMaxHDOP(maxHDOP float64) Filter {
+	return filterBy(func(dp DataPoint) bool {
+		return dp.HDOP == 0 || dp.HDOP <= maxHDOP
+	})
+}
+
+// FilterByMinFixType drops all data points with a fix type below the given minimum. Data points with unknown fix
+// type are kept.
+func FilterByMinFixType(minFixType FixType) Filter {
+	return filterBy(func(dp DataPoint) bool {
+		return dp.FixType == UnknownFix || dp.FixType >= minFixType
+	})
+}
+
+// FilterBySpeed keeps only data points with a speed between the given minimum and maximum in m/s. A maximum of 0
+// means no upper limit. Data points with unknown speed are kept: the speed is unknown if it is 0 and the data point
+// has no fix type, e.g. in trace files of older versions.
+func FilterBySpeed(minSpeed, maxSpeed float64) Filter {
+	return filterBy(func(dp DataPoint) bool {
+		if dp.Speed == 0 && dp.FixType == UnknownFix {
+			return true
+		}
+		return dp.Speed >= minSpeed && (maxSpeed == 0 || dp.Speed <= maxSpeed)
+	})
+}
+
 func FilterByLAC(lac uint32) Filter {
 	return FilterFunc(func(dataPoints []DataPoint) []DataPoint {
 		result := make([]DataPoint, 0, len(dataPoints))
@@ -36,6 +108,18 @@ func FilterByCarrier(carrier uint32) Filter {
 	})
 }
 
+func FilterServingCell() Filter {
+	return FilterFunc(func(dataPoints []DataPoint) []DataPoint {
+		result := make([]DataPoint, 0, len(dataPoints))
+		for _, dp := range dataPoints {
+			if dp.Serving && dp.IsValid() {
+				result = append(result, dp)
+			}
+		}
+		return SortByTimestamp(result)
+	})
+}
+
 func FilterBestServer() Filter {
 	return FilterFunc(func(dataPoints []DataPoint) []DataPoint {
 		byTimeAndSpace := make(map[string][]DataPoint)
@@ -0,0 +1,25 @@
+package data
+
+import "testing"
+
+func TestFilterBySpeed(t *testing.T) {
+	tt := []struct {
+		name      string
+		dataPoint DataPoint
+		expected  bool
+	}{
+		{name: "moving", dataPoint: DataPoint{Speed: 10, FixType: Fix2D}, expected: true},
+		{name: "parked with fix", dataPoint: DataPoint{Speed: 0, FixType: Fix2D}, expected: false},
+		{name: "too fast", dataPoint: DataPoint{Speed: 40, FixType: Fix3D}, expected: false},
+		{name: "unknown speed", dataPoint: DataPoint{Speed: 0, FixType: UnknownFix}, expected: true},
+	}
+	filter := FilterBySpeed(2, 30)
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			actual := len(filter.Filter([]DataPoint{tc.dataPoint})) == 1
+			if actual != tc.expected {
+				t.Errorf("expected %t, got %t", tc.expected, actual)
+			}
+		})
+	}
+}
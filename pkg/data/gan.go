@@ -0,0 +1,132 @@
+package data
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// GANProfile defines the RSSI thresholds for the GAN levels. Different operators and planning targets, e.g. indoor
+// coverage, use different thresholds.
+type GANProfile struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	// Thresholds contains the minimum RSSI in dBm for the GAN levels -1 to 4, in ascending order.
+	// A lower RSSI is GAN -2.
+	Thresholds [6]int `json:"thresholds"`
+	// Usable is the minimum RSSI in dBm of a usable server.
+	Usable int `json:"usable"`
+}
+
+var DefaultGANProfile = GANProfile{
+	Name:        "default",
+	Description: "vehicle mounted radio outdoors",
+	Thresholds:  [6]int{-103, -97, -94, -88, -85, -79},
+	Usable:      -94,
+}
+
+// BuiltinGANProfiles are always available. The indoor profile adds a building penetration margin of 10 dB, the
+// handheld profile a body loss margin of 6 dB to the default profile.
+var BuiltinGANProfiles = []GANProfile{
+	DefaultGANProfile,
+	DefaultGANProfile.withMargin("indoor", "handheld radio inside buildings", 10),
+	DefaultGANProfile.withMargin("handheld", "handheld radio outdoors", 6),
+}
+
+func (p GANProfile) withMargin(name string, description string, margin int) GANProfile {
+	result := GANProfile{
+		Name:        name,
+		Description: description,
+		Usable:      p.Usable + margin,
+	}
+	for i, threshold := range p.Thresholds {
+		result.Thresholds[i] = threshold + margin
+	}
+	return result
+}
+
+func (p GANProfile) RSSIToGAN(rssi int) int {
+	if rssi == NoSignal {
+		return NoGAN
+	}
+	result := -2
+	for _, threshold := range p.Thresholds {
+		if rssi < threshold {
+			break
+		}
+		result++
+	}
+	return result
+}
+
+func (p GANProfile) IsUsableRSSI(rssi int) bool {
+	return rssi != NoSignal && rssi >= p.Usable
+}
+
+// Validate checks that the thresholds are in ascending order and that the usable threshold is within the range of
+// the thresholds.
+func (p GANProfile) Validate() error {
+	if p.Name == "" {
+		return fmt.Errorf("the GAN profile has no name")
+	}
+	for i := 1; i < len(p.Thresholds); i++ {
+		if p.Thresholds[i] <= p.Thresholds[i-1] {
+			return fmt.Errorf("the thresholds of the GAN profile %s must be in ascending order", p.Name)
+		}
+	}
+	lowest, highest := p.Thresholds[0], p.Thresholds[len(p.Thresholds)-1]
+	if p.Usable < lowest || p.Usable > highest {
+		return fmt.Errorf("the usable threshold of the GAN profile %s must be between %d and %d dBm", p.Name, lowest, highest)
+	}
+	return nil
+}
+
+// Legend describes the profile in one line, e.g. for the legend of a report.
+func (p GANProfile) Legend() string {
+	levels := make([]string, 0, len(p.Thresholds)+2)
+	levels = append(levels, fmt.Sprintf("GAN -2: < %d dBm", p.Thresholds[0]))
+	for i, threshold := range p.Thresholds {
+		levels = append(levels, fmt.Sprintf("GAN %d: >= %d dBm", i-1, threshold))
+	}
+	levels = append(levels, fmt.Sprintf("usable: >= %d dBm", p.Usable))
+
+	name := p.Name
+	if p.Description != "" {
+		name += " (" + p.Description + ")"
+	}
+	return fmt.Sprintf("GAN profile %s: %s", name, strings.Join(levels, ", "))
+}
+
+// ReadGANProfiles reads user defined GAN profiles from a JSON document of the form
+//
+//	{"profiles": [{"name": "...", "description": "...", "thresholds": [-103, -97, -94, -88, -85, -79], "usable": -94}]}
+func ReadGANProfiles(in io.Reader) ([]GANProfile, error) {
+	var document struct {
+		Profiles []GANProfile `json:"profiles"`
+	}
+	err := json.NewDecoder(in).Decode(&document)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing GAN profiles: %w", err)
+	}
+	for _, profile := range document.Profiles {
+		err := profile.Validate()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return document.Profiles, nil
+}
+
+// FindGANProfile returns the profile with the given name. User defined profiles take precedence over the built-in
+// profiles.
+func FindGANProfile(name string, userProfiles []GANProfile) (GANProfile, error) {
+	for _, profiles := range [][]GANProfile{userProfiles, BuiltinGANProfiles} {
+		for _, profile := range profiles {
+			if strings.EqualFold(profile.Name, name) {
+				return profile, nil
+			}
+		}
+	}
+	return GANProfile{}, fmt.Errorf("unknown GAN profile: %s", name)
+}
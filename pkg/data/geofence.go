@@ -0,0 +1,140 @@
+package data
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Area is a geographic area that is used to filter data points by their position.
+type Area interface {
+	Contains(lat, lon float64) bool
+}
+
+// InArea keeps the data points with a position inside the given area.
+func InArea(area Area) Predicate {
+	return func(dp DataPoint) bool {
+		return area.Contains(dp.Latitude, dp.Longitude)
+	}
+}
+
+// Areas is the union of several areas.
+type Areas []Area
+
+func (a Areas) Contains(lat, lon float64) bool {
+	for _, area := range a {
+		if area.Contains(lat, lon) {
+			return true
+		}
+	}
+	return false
+}
+
+type BoundingBox struct {
+	MinLat float64
+	MinLon float64
+	MaxLat float64
+	MaxLon float64
+}
+
+// ParseBoundingBox parses a bounding box in the form minLat,minLon,maxLat,maxLon.
+func ParseBoundingBox(s string) (BoundingBox, error) {
+	values, err := parseCoordinateList(s, 4)
+	if err != nil {
+		return BoundingBox{}, fmt.Errorf("invalid bounding box %q: %w", s, err)
+	}
+	return BoundingBox{
+		MinLat: min(values[0], values[2]),
+		MinLon: min(values[1], values[3]),
+		MaxLat: max(values[0], values[2]),
+		MaxLon: max(values[1], values[3]),
+	}, nil
+}
+
+func (b BoundingBox) Contains(lat, lon float64) bool {
+	return lat >= b.MinLat && lat <= b.MaxLat && lon >= b.MinLon && lon <= b.MaxLon
+}
+
+// Circle is the area within the given radius in metres around a center point.
+type Circle struct {
+	Latitude  float64
+	Longitude float64
+	Radius    float64
+}
+
+// ParseCircle parses a circle in the form lat,lon,radius with the radius in metres.
+func ParseCircle(s string) (Circle, error) {
+	values, err := parseCoordinateList(s, 3)
+	if err != nil {
+		return Circle{}, fmt.Errorf("invalid circle %q: %w", s, err)
+	}
+	if values[2] <= 0 {
+		return Circle{}, fmt.Errorf("invalid circle %q: the radius must be positive", s)
+	}
+	return Circle{
+		Latitude:  values[0],
+		Longitude: values[1],
+		Radius:    values[2],
+	}, nil
+}
+
+func (c Circle) Contains(lat, lon float64) bool {
+	return Distance(c.Latitude, c.Longitude, lat, lon) <= c.Radius
+}
+
+type LatLon struct {
+	Latitude  float64
+	Longitude float64
+}
+
+// Polygon is the area within the outer ring, except the areas within the holes. The rings do not need to be closed.
+type Polygon struct {
+	Outer []LatLon
+	Holes [][]LatLon
+}
+
+func (p Polygon) Contains(lat, lon float64) bool {
+	if !ringContains(p.Outer, lat, lon) {
+		return false
+	}
+	for _, hole := range p.Holes {
+		if ringContains(hole, lat, lon) {
+			return false
+		}
+	}
+	return true
+}
+
+// ringContains implements the even-odd rule. The coordinates are treated as plane coordinates, which is precise
+// enough for the size of the areas in question.
+func ringContains(ring []LatLon, lat, lon float64) bool {
+	result := false
+	for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+		a := ring[i]
+		b := ring[j]
+		if (a.Latitude > lat) == (b.Latitude > lat) {
+			continue
+		}
+		crossingLon := a.Longitude + (lat-a.Latitude)*(b.Longitude-a.Longitude)/(b.Latitude-a.Latitude)
+		if lon < crossingLon {
+			result = !result
+		}
+	}
+	return result
+}
+
+func parseCoordinateList(s string, count int) ([]float64, error) {
+	fields := strings.Split(s, ",")
+	if len(fields) != count {
+		return nil, fmt.Errorf("expected %d comma separated values, got %d", count, len(fields))
+	}
+	result := make([]float64, count)
+	for i, field := range fields {
+		value, err := strconv.ParseFloat(strings.TrimSpace(field), 64)
+		if err != nil {
+			return nil, err
+		}
+		result[i] = value
+	}
+	return result, nil
+}
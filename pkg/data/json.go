@@ -3,6 +3,7 @@ package data
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"strings"
 )
 
@@ -11,6 +12,15 @@ func DataPointToJSON(dataPoint DataPoint) string {
 	return string(encoded)
 }
 
+// WriteJSONHeader writes the metadata as header record.
+func WriteJSONHeader(out io.Writer, metadata Metadata) error {
+	if metadata.IsZero() {
+		return nil
+	}
+	_, err := fmt.Fprintln(out, MetadataToJSON(metadata))
+	return err
+}
+
 func IsJSONLine(line string) bool {
 	return strings.HasPrefix(line, "{") && strings.HasSuffix(line, "}") && (strings.Contains(line, "\"") || !strings.Contains(line, "'")) && strings.Contains(line, ":")
 }
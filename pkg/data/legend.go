@@ -0,0 +1,110 @@
+package data
+
+import (
+	"encoding/json"
+	"fmt"
+	"image/color"
+	"strings"
+)
+
+// Legend describes how the signal quality is represented on a map: the GAN profile defines the levels, the color
+// scheme the colors.
+type Legend struct {
+	Profile GANProfile
+	Colors  ColorScheme
+	// ImageHref is the reference to an image of the legend, e.g. for a KML ScreenOverlay. It is optional.
+	ImageHref string
+}
+
+// LegendEntry describes the color of one GAN level.
+type LegendEntry struct {
+	GAN   int
+	Label string
+	Color color.Color
+}
+
+func NewLegend(profile GANProfile, colors ColorScheme) Legend {
+	return Legend{
+		Profile: profile,
+		Colors:  colors,
+	}
+}
+
+// DefaultLegend uses the default GAN profile and the default color scheme.
+var DefaultLegend = NewLegend(DefaultGANProfile, DefaultColorScheme)
+
+// Color returns the color of the given RSSI.
+func (l Legend) Color(rssi int) color.Color {
+	return l.Colors.RSSIColor(l.Profile, rssi)
+}
+
+// GANColor returns the color of the given GAN level.
+func (l Legend) GANColor(gan int) color.Color {
+	return l.Colors.GANColor(gan)
+}
+
+// Entries returns the legend entries from the best to the worst GAN level, followed by the entry for no signal.
+// For gradient schemes, the color of an entry is the color at the threshold of the level.
+func (l Legend) Entries() []LegendEntry {
+	result := make([]LegendEntry, 0, len(l.Profile.Thresholds)+2)
+	for i := len(l.Profile.Thresholds) - 1; i >= 0; i-- {
+		threshold := l.Profile.Thresholds[i]
+		result = append(result, LegendEntry{
+			GAN:   i - 1,
+			Label: fmt.Sprintf("GAN %d: >= %d dBm", i-1, threshold),
+			Color: l.Color(threshold),
+		})
+	}
+	result = append(result,
+		LegendEntry{
+			GAN:   -2,
+			Label: fmt.Sprintf("GAN -2: < %d dBm", l.Profile.Thresholds[0]),
+			Color: l.GANColor(-2),
+		},
+		LegendEntry{
+			GAN:   NoGAN,
+			Label: "no signal",
+			Color: l.Color(NoSignal),
+		},
+	)
+	return result
+}
+
+// Title describes the GAN profile and the color scheme of the legend in a few words.
+func (l Legend) Title() string {
+	return fmt.Sprintf("GAN profile %s, colors %s", l.Profile.Name, l.Colors.Name)
+}
+
+// String describes the legend in one line, e.g. for the description of a map.
+func (l Legend) String() string {
+	entries := l.Entries()
+	colors := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		colors = append(colors, fmt.Sprintf("%s %s", entry.Label, ColorToHex(entry.Color)))
+	}
+	return fmt.Sprintf("%s; color scheme %s: %s", l.Profile.Legend(), l.Colors.Name, strings.Join(colors, ", "))
+}
+
+func (l Legend) MarshalJSON() ([]byte, error) {
+	type jsonEntry struct {
+		Label string `json:"label"`
+		Color string `json:"color"`
+	}
+	entries := l.Entries()
+	jsonEntries := make([]jsonEntry, 0, len(entries))
+	for _, entry := range entries {
+		jsonEntries = append(jsonEntries, jsonEntry{Label: entry.Label, Color: ColorToHex(entry.Color)})
+	}
+
+	return json.Marshal(struct {
+		GANProfile  string      `json:"gan_profile"`
+		ColorScheme string      `json:"color_scheme"`
+		Gradient    bool        `json:"gradient,omitempty"`
+		Entries     []jsonEntry `json:"entries"`
+	}{
+		GANProfile:  l.Profile.Name,
+		ColorScheme: l.Colors.Name,
+		Gradient:    l.Colors.Gradient,
+		Entries:     jsonEntries,
+	})
+}
@@ -0,0 +1,213 @@
+package data
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+)
+
+const metadataCommentPrefix = "# meta "
+
+// Metadata describes how a trace file was recorded.
+type Metadata struct {
+	Version  string      `json:"version,omitempty"`
+	Started  time.Time   `json:"started,omitzero"`
+	Operator string      `json:"operator,omitempty"`
+	Vehicle  string      `json:"vehicle,omitempty"`
+	Antenna  string      `json:"antenna,omitempty"`
+	Radios   []RadioInfo `json:"radios,omitempty"`
+}
+
+// RadioInfo describes a radio that was used to record a trace file. The device name is empty for a single radio.
+type RadioInfo struct {
+	Device       string `json:"device,omitempty"`
+	Manufacturer string `json:"manufacturer,omitempty"`
+	Model        string `json:"model,omitempty"`
+	Firmware     string `json:"firmware,omitempty"`
+	ISSI         string `json:"issi,omitempty"`
+}
+
+func (m Metadata) IsZero() bool {
+	return m.Version == "" && m.Started.IsZero() && m.Operator == "" && m.Vehicle == "" && m.Antenna == "" && len(m.Radios) == 0
+}
+
+// Title returns a short description of the trace, e.g. to name the result of an evaluation.
+func (m Metadata) Title() string {
+	parts := make([]string, 0, 3)
+	if !m.Started.IsZero() {
+		parts = append(parts, m.Started.Format("2006-01-02 15:04"))
+	}
+	if m.Vehicle != "" {
+		parts = append(parts, m.Vehicle)
+	}
+	if m.Operator != "" {
+		parts = append(parts, m.Operator)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Merge completes this metadata with the given other metadata. Fields that are already set are kept, except the
+// start time, where the earlier one is used. Radios are added if they are not known yet.
+func (m Metadata) Merge(other Metadata) Metadata {
+	if m.Version == "" {
+		m.Version = other.Version
+	}
+	if m.Started.IsZero() || (!other.Started.IsZero() && other.Started.Before(m.Started)) {
+		m.Started = other.Started
+	}
+	if m.Operator == "" {
+		m.Operator = other.Operator
+	}
+	if m.Vehicle == "" {
+		m.Vehicle = other.Vehicle
+	}
+	if m.Antenna == "" {
+		m.Antenna = other.Antenna
+	}
+	m.Radios = slices.Clone(m.Radios)
+	for _, radio := range other.Radios {
+		if !slices.Contains(m.Radios, radio) {
+			m.Radios = append(m.Radios, radio)
+		}
+	}
+	return m
+}
+
+func (m *Metadata) radio(device string) *RadioInfo {
+	for i := range m.Radios {
+		if m.Radios[i].Device == device {
+			return &m.Radios[i]
+		}
+	}
+	m.Radios = append(m.Radios, RadioInfo{Device: device})
+	return &m.Radios[len(m.Radios)-1]
+}
+
+// MetadataToCSV returns the metadata as comment lines of the form "# meta <key>: <value>". The keys of the radio
+// information are prefixed with radio., followed by the device name in brackets for named devices,
+// e.g. "# meta radio.model[front]: MTM5400".
+func MetadataToCSV(metadata Metadata) string {
+	lines := make([]string, 0, 5+4*len(metadata.Radios))
+	add := func(key, value string) {
+		value = strings.TrimSpace(strings.ReplaceAll(value, "\n", " "))
+		if value != "" {
+			lines = append(lines, fmt.Sprintf("%s%s: %s", metadataCommentPrefix, key, value))
+		}
+	}
+	add("version", metadata.Version)
+	if !metadata.Started.IsZero() {
+		add("started", metadata.Started.Format(time.RFC3339))
+	}
+	add("operator", metadata.Operator)
+	add("vehicle", metadata.Vehicle)
+	add("antenna", metadata.Antenna)
+	for _, radio := range metadata.Radios {
+		suffix := ""
+		if radio.Device != "" {
+			suffix = "[" + radio.Device + "]"
+		}
+		add("radio.manufacturer"+suffix, radio.Manufacturer)
+		add("radio.model"+suffix, radio.Model)
+		add("radio.firmware"+suffix, radio.Firmware)
+		add("radio.issi"+suffix, radio.ISSI)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// MetadataToJSON returns the metadata as header record of the form {"metadata":{...}}.
+func MetadataToJSON(metadata Metadata) string {
+	encoded, _ := json.Marshal(metadataRecord{Metadata: &metadata})
+	return string(encoded)
+}
+
+type metadataRecord struct {
+	Metadata *Metadata `json:"metadata"`
+}
+
+// IsMetadataRecord returns true if the given line is a JSON header record with metadata.
+func IsMetadataRecord(line string) bool {
+	return strings.HasPrefix(line, `{"metadata":`)
+}
+
+// ReadMetadata reads the metadata from the header of a trace file. It stops at the first data line. Compressed
+// content is decompressed transparently.
+func ReadMetadata(in io.Reader) (Metadata, error) {
+	decompressed, err := Decompress(in)
+	if err != nil {
+		return Metadata{}, err
+	}
+	defer decompressed.Close()
+
+	var result Metadata
+	lineScanner := bufio.NewScanner(decompressed)
+	for lineScanner.Scan() {
+		line := strings.TrimSpace(lineScanner.Text())
+		switch {
+		case line == "":
+			continue
+		case strings.HasPrefix(line, metadataCommentPrefix):
+			parseMetadataComment(&result, line[len(metadataCommentPrefix):])
+		case line[0] == '#' || IsCSVHeader(line):
+			continue
+		case IsMetadataRecord(line):
+			record := metadataRecord{Metadata: &result}
+			err := json.Unmarshal([]byte(line), &record)
+			if err != nil {
+				return Metadata{}, fmt.Errorf("error parsing metadata record: %w", err)
+			}
+		default:
+			return result, nil
+		}
+	}
+	return result, lineScanner.Err()
+}
+
+func parseMetadataComment(metadata *Metadata, comment string) {
+	key, value, ok := strings.Cut(comment, ":")
+	if !ok {
+		return
+	}
+	key = strings.TrimSpace(key)
+	value = strings.TrimSpace(value)
+
+	switch key {
+	case "version":
+		metadata.Version = value
+	case "started":
+		started, err := time.Parse(time.RFC3339, value)
+		if err == nil {
+			metadata.Started = started
+		}
+	case "operator":
+		metadata.Operator = value
+	case "vehicle":
+		metadata.Vehicle = value
+	case "antenna":
+		metadata.Antenna = value
+	}
+
+	radioKey, found := strings.CutPrefix(key, "radio.")
+	if !found {
+		return
+	}
+	var device string
+	if open := strings.Index(radioKey, "["); open != -1 && strings.HasSuffix(radioKey, "]") {
+		device = radioKey[open+1 : len(radioKey)-1]
+		radioKey = radioKey[:open]
+	}
+	radio := metadata.radio(device)
+	switch radioKey {
+	case "manufacturer":
+		radio.Manufacturer = value
+	case "model":
+		radio.Model = value
+	case "firmware":
+		radio.Firmware = value
+	case "issi":
+		radio.ISSI = value
+	}
+}
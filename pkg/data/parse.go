@@ -5,13 +5,25 @@ import (
 	"strings"
 )
 
+// ParseDecOrHex parses a decimal or a hexadecimal value. A value is hexadecimal if it has a 0x prefix or contains
+// any of the letters a-f.
 func ParseDecOrHex(s string) (uint32, error) {
-	var result uint64
-	var err error
-	if strings.ContainsAny(s, "ABCDEFabcdef") {
-		result, err = strconv.ParseUint(s, 16, 32)
-	} else {
-		result, err = strconv.ParseUint(s, 10, 32)
+	if hasHexPrefix(s) || strings.ContainsAny(s, "ABCDEFabcdef") {
+		return ParseHex(s)
 	}
+	result, err := strconv.ParseUint(s, 10, 32)
 	return uint32(result), err
 }
+
+// ParseHex parses a hexadecimal value with or without 0x prefix.
+func ParseHex(s string) (uint32, error) {
+	if hasHexPrefix(s) {
+		s = s[2:]
+	}
+	result, err := strconv.ParseUint(s, 16, 32)
+	return uint32(result), err
+}
+
+func hasHexPrefix(s string) bool {
+	return len(s) > 2 && s[0] == '0' && (s[1] == 'x' || s[1] == 'X')
+}
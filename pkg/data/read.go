@@ -2,36 +2,124 @@ package data
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	"io"
-	"log"
+	"iter"
 	"strings"
 )
 
-func ReadDataPoints(in io.Reader) ([]DataPoint, error) {
-	lines, err := ReadLines(in)
-	if err != nil {
-		return nil, err
+// LineError describes a line of a trace file that cannot be parsed.
+type LineError struct {
+	Line int
+	Text string
+	Err  error
+}
+
+func (e *LineError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+func (e *LineError) Unwrap() error {
+	return e.Err
+}
+
+// DataPoints iterates over the data points of a trace file without loading the whole file into memory.
+// Compressed content is decompressed transparently. Lines that cannot be parsed are yielded as *LineError and
+// the iteration continues. Any other error ends the iteration.
+func DataPoints(in io.Reader) iter.Seq2[DataPoint, error] {
+	return func(yield func(DataPoint, error) bool) {
+		decompressed, err := Decompress(in)
+		if err != nil {
+			yield(DataPoint{}, err)
+			return
+		}
+		defer decompressed.Close()
+
+		csvSchema := LegacyCSVSchema
+		lineScanner := bufio.NewScanner(decompressed)
+		lineNumber := 0
+		for lineScanner.Scan() {
+			lineNumber++
+			line := strings.TrimSpace(lineScanner.Text())
+			if line == "" || line[0] == '#' {
+				continue
+			}
+
+			var dataPoint DataPoint
+			var err error
+			switch {
+			case IsCSVHeader(line):
+				csvSchema, err = ParseCSVHeader(line)
+				if err != nil {
+					yield(DataPoint{}, fmt.Errorf("error parsing CSV header in line %d: %w", lineNumber, err))
+					return
+				}
+				continue
+			case IsMetadataRecord(line):
+				continue
+			case IsCSVLine(line):
+				dataPoint, err = csvSchema.ParseLine(line)
+			case IsJSONLine(line):
+				dataPoint, err = ParseJSONLine(line)
+			default:
+				err = fmt.Errorf("unknown line format: %s", line)
+			}
+			if err != nil {
+				err = &LineError{Line: lineNumber, Text: line, Err: err}
+			}
+			if !yield(dataPoint, err) {
+				return
+			}
+		}
+		if err := lineScanner.Err(); err != nil {
+			yield(DataPoint{}, err)
+		}
 	}
+}
 
-	result := make([]DataPoint, 0, len(lines))
-	for i, line := range lines {
-		var dataPoint DataPoint
-		switch {
-		case IsCSVLine(line):
-			dataPoint, err = ParseCSVLine(line)
-		case IsJSONLine(line):
-			dataPoint, err = ParseJSONLine(line)
-		default:
-			err = fmt.Errorf("unknown line format: %s", line)
+// Measurements groups consecutive data points of the same measurement. Errors are passed through.
+func Measurements(dataPoints iter.Seq2[DataPoint, error]) iter.Seq2[[]DataPoint, error] {
+	return func(yield func([]DataPoint, error) bool) {
+		var measurement []DataPoint
+		for dataPoint, err := range dataPoints {
+			if err != nil {
+				if !yield(nil, err) {
+					return
+				}
+				continue
+			}
+			if len(measurement) > 0 && measurement[0].MeasurementID() != dataPoint.MeasurementID() {
+				if !yield(measurement, nil) {
+					return
+				}
+				measurement = nil
+			}
+			measurement = append(measurement, dataPoint)
 		}
-		if err != nil {
-			log.Printf("Error parsing line %d: %v\n", i+1, err)
+		if len(measurement) > 0 {
+			yield(measurement, nil)
+		}
+	}
+}
+
+// ReadDataPoints reads all data points of a trace file. Lines that cannot be parsed are skipped and returned as
+// line errors. Any other error ends the reading.
+func ReadDataPoints(in io.Reader) ([]DataPoint, []*LineError, error) {
+	result := make([]DataPoint, 0)
+	var lineErrs []*LineError
+	for dataPoint, err := range DataPoints(in) {
+		var lineErr *LineError
+		if errors.As(err, &lineErr) {
+			lineErrs = append(lineErrs, lineErr)
 			continue
 		}
+		if err != nil {
+			return nil, lineErrs, err
+		}
 		result = append(result, dataPoint)
 	}
-	return result, nil
+	return result, lineErrs, nil
 }
 
 func ReadLines(in io.Reader) ([]string, error) {
@@ -55,6 +55,16 @@ func (p *DemoPEI) AT(ctx context.Context, request string) ([]string, error) {
 		return p.currentSignalStrength()
 	case "AT+GCLI?":
 		return p.currentCellListInfo()
+	case "AT+CREG?":
+		return []string{"+CREG: 1,12345,26200001"}, nil
+	case "AT+GMI":
+		return []string{"Motorola Solutions"}, nil
+	case "AT+GMM":
+		return []string{"MTM5400"}, nil
+	case "AT+GMR":
+		return []string{"R23.150.2202"}, nil
+	case "AT+CNUMF?":
+		return []string{"+CNUMF: 6,262100001234567"}, nil
 	default:
 		return []string{"OK"}, nil
 	}
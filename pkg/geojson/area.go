@@ -0,0 +1,95 @@
+package geojson
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/ftl/tetra-mess/pkg/data"
+)
+
+// geoJSONObject is used to read any kind of GeoJSON object: a feature collection, a feature or a geometry.
+type geoJSONObject struct {
+	Type        string          `json:"type"`
+	Features    []geoJSONObject `json:"features"`
+	Geometry    *geoJSONObject  `json:"geometry"`
+	Geometries  []geoJSONObject `json:"geometries"`
+	Coordinates json.RawMessage `json:"coordinates"`
+}
+
+// ReadAreas reads all polygons and multi polygons of the given GeoJSON document, e.g. the boundary of a
+// municipality. Other geometries are ignored.
+func ReadAreas(in io.Reader) (data.Areas, error) {
+	var object geoJSONObject
+	err := json.NewDecoder(in).Decode(&object)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing GeoJSON: %w", err)
+	}
+
+	var result data.Areas
+	err = collectAreas(object, &result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func collectAreas(object geoJSONObject, areas *data.Areas) error {
+	switch object.Type {
+	case "FeatureCollection":
+		for _, feature := range object.Features {
+			err := collectAreas(feature, areas)
+			if err != nil {
+				return err
+			}
+		}
+	case "Feature":
+		if object.Geometry != nil {
+			return collectAreas(*object.Geometry, areas)
+		}
+	case "GeometryCollection":
+		for _, geometry := range object.Geometries {
+			err := collectAreas(geometry, areas)
+			if err != nil {
+				return err
+			}
+		}
+	case "Polygon":
+		var coordinates [][][]float64
+		err := json.Unmarshal(object.Coordinates, &coordinates)
+		if err != nil {
+			return fmt.Errorf("invalid polygon coordinates: %w", err)
+		}
+		*areas = append(*areas, toPolygon(coordinates))
+	case "MultiPolygon":
+		var coordinates [][][][]float64
+		err := json.Unmarshal(object.Coordinates, &coordinates)
+		if err != nil {
+			return fmt.Errorf("invalid multi polygon coordinates: %w", err)
+		}
+		for _, polygon := range coordinates {
+			*areas = append(*areas, toPolygon(polygon))
+		}
+	}
+	return nil
+}
+
+// toPolygon converts the rings of a GeoJSON polygon: the first ring is the outer ring, all others are holes.
+func toPolygon(rings [][][]float64) data.Polygon {
+	var result data.Polygon
+	for i, ring := range rings {
+		converted := make([]data.LatLon, 0, len(ring))
+		for _, position := range ring {
+			if len(position) < 2 {
+				continue
+			}
+			converted = append(converted, data.LatLon{Latitude: position[1], Longitude: position[0]})
+		}
+		if i == 0 {
+			result.Outer = converted
+		} else {
+			result.Holes = append(result.Holes, converted)
+		}
+	}
+	return result
+}
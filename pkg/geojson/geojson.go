@@ -0,0 +1,143 @@
+package geojson
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/ftl/tetra-mess/pkg/data"
+	"github.com/ftl/tetra-mess/pkg/quality"
+)
+
+// FeatureCollection is the root object of a GeoJSON document (RFC 7946).
+type FeatureCollection struct {
+	Type       string           `json:"type"`
+	Name       string           `json:"name,omitempty"`
+	GANProfile *data.GANProfile `json:"gan_profile,omitempty"`
+	Legend     *data.Legend     `json:"legend,omitempty"`
+	Features   []Feature        `json:"features"`
+}
+
+type Feature struct {
+	Type       string   `json:"type"`
+	Geometry   Geometry `json:"geometry"`
+	Properties any      `json:"properties"`
+}
+
+// Geometry holds either a point ([lon, lat]) or a polygon ([][][lon, lat]) as coordinates.
+type Geometry struct {
+	Type        string `json:"type"`
+	Coordinates any    `json:"coordinates"`
+}
+
+func NewFeatureCollection(name string, features []Feature) FeatureCollection {
+	return FeatureCollection{
+		Type:     "FeatureCollection",
+		Name:     name,
+		Features: features,
+	}
+}
+
+func Point(lat, lon float64) Geometry {
+	return Geometry{
+		Type:        "Point",
+		Coordinates: []float64{lon, lat},
+	}
+}
+
+// Rectangle returns a polygon geometry that covers the given area.
+func Rectangle(minLat, minLon, maxLat, maxLon float64) Geometry {
+	return Geometry{
+		Type: "Polygon",
+		Coordinates: [][][]float64{{
+			{minLon, maxLat},
+			{minLon, minLat},
+			{maxLon, minLat},
+			{maxLon, maxLat},
+			{minLon, maxLat},
+		}},
+	}
+}
+
+func Write(out io.Writer, collection FeatureCollection) error {
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	err := encoder.Encode(collection)
+	if err != nil {
+		return fmt.Errorf("error writing GeoJSON to output: %w", err)
+	}
+	return nil
+}
+
+// WriteDataPointsAsGeoJSON writes the data points as points. The color of a point is given as marker-color property
+// (simplestyle-spec), which is used by many GeoJSON viewers.
+func WriteDataPointsAsGeoJSON(out io.Writer, name string, legend data.Legend, dataPoints []data.DataPoint) error {
+	features := make([]Feature, 0, len(dataPoints))
+	for _, dataPoint := range dataPoints {
+		if dataPoint.Latitude == 0 && dataPoint.Longitude == 0 {
+			continue // Skip points without valid coordinates
+		}
+		features = append(features, dataPointToFeature(legend, dataPoint))
+	}
+	collection := NewFeatureCollection(name, features)
+	collection.GANProfile = &legend.Profile
+	collection.Legend = &legend
+	return Write(out, collection)
+}
+
+func dataPointToFeature(legend data.Legend, dataPoint data.DataPoint) Feature {
+	properties := map[string]any{
+		"ts":      dataPoint.Timestamp.Format(time.RFC3339),
+		"sats":    dataPoint.Satellites,
+		"lac":     dataPoint.LAC,
+		"carrier": fmt.Sprintf("%x", dataPoint.Carrier),
+		"rssi":    dataPoint.RSSI,
+		"cx":      dataPoint.Cx,
+		"gan":     legend.Profile.RSSIToGAN(dataPoint.RSSI),
+		"serving": dataPoint.Serving,
+
+		"marker-color": data.ColorToHex(legend.Color(dataPoint.RSSI)),
+	}
+	if dataPoint.Device != "" {
+		properties["device"] = dataPoint.Device
+	}
+	if dataPoint.GPSIssue != data.NoGPSIssue {
+		properties["gps_issue"] = dataPoint.GPSIssue.String()
+	}
+	return Feature{
+		Type:       "Feature",
+		Geometry:   Point(dataPoint.Latitude, dataPoint.Longitude),
+		Properties: properties,
+	}
+}
+
+// fieldProperties adds the fill color of the field (simplestyle-spec) to the field summary.
+type fieldProperties struct {
+	quality.FieldSummary
+	Fill string `json:"fill"`
+}
+
+// WriteFieldReportsAsGeoJSON writes the fields as polygons. The color of a field is given as fill property
+// (simplestyle-spec).
+func WriteFieldReportsAsGeoJSON(out io.Writer, name string, legend data.Legend, fieldReports []quality.FieldReport) error {
+	features := make([]Feature, 0, len(fieldReports))
+	for _, fieldReport := range fieldReports {
+		minLat, minLon, maxLat, maxLon := fieldReport.Area()
+		if minLat == 0 && minLon == 0 && maxLat == 0 && maxLon == 0 {
+			continue // Skip fields without valid area
+		}
+		features = append(features, Feature{
+			Type:     "Feature",
+			Geometry: Rectangle(minLat, minLon, maxLat, maxLon),
+			Properties: fieldProperties{
+				FieldSummary: fieldReport.Summary(),
+				Fill:         data.ColorToHex(legend.Color(fieldReport.AverageRSSI())),
+			},
+		})
+	}
+	collection := NewFeatureCollection(name, features)
+	collection.GANProfile = &legend.Profile
+	collection.Legend = &legend
+	return Write(out, collection)
+}
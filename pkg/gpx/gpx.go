@@ -7,17 +7,19 @@ import (
 	"github.com/tkrajina/gpxgo/gpx"
 
 	"github.com/ftl/tetra-mess/pkg/data"
+	"github.com/ftl/tetra-mess/pkg/quality"
 )
 
-func WriteDataPointsAsGPX(out io.Writer, name string, dataPoints []data.DataPoint) error {
-	waypoints := dataPointsToGPXPoints(dataPoints)
-	track := dataPointsToGPXTrack(dataPoints)
+func WriteDataPointsAsGPX(out io.Writer, name string, legend data.Legend, dataPoints []data.DataPoint) error {
+	waypoints := dataPointsToGPXPoints(legend.Profile, dataPoints)
+	track := dataPointsToGPXTrack(legend.Profile, dataPoints)
 	result := gpx.GPX{
-		Version:   "1.1",
-		Creator:   "tetra-mess",
-		Name:      name,
-		Tracks:    []gpx.GPXTrack{track},
-		Waypoints: waypoints,
+		Version:     "1.1",
+		Creator:     "tetra-mess",
+		Name:        name,
+		Description: legend.String(),
+		Tracks:      []gpx.GPXTrack{track},
+		Waypoints:   waypoints,
 	}
 
 	bytes, err := gpx.ToXml(&result, gpx.ToXmlParams{
@@ -36,8 +38,8 @@ func WriteDataPointsAsGPX(out io.Writer, name string, dataPoints []data.DataPoin
 	return nil
 }
 
-func dataPointsToGPXTrack(dataPoints []data.DataPoint) gpx.GPXTrack {
-	gpxPoints := dataPointsToGPXPoints(dataPoints)
+func dataPointsToGPXTrack(profile data.GANProfile, dataPoints []data.DataPoint) gpx.GPXTrack {
+	gpxPoints := dataPointsToGPXPoints(profile, dataPoints)
 	segment := gpx.GPXTrackSegment{
 		Points: gpxPoints,
 	}
@@ -52,30 +54,86 @@ func dataPointsToGPXTrack(dataPoints []data.DataPoint) gpx.GPXTrack {
 	return track
 }
 
-func dataPointsToGPXPoints(dataPoints []data.DataPoint) []gpx.GPXPoint {
+func dataPointsToGPXPoints(profile data.GANProfile, dataPoints []data.DataPoint) []gpx.GPXPoint {
 	result := make([]gpx.GPXPoint, 0, len(dataPoints))
 	for _, dataPoint := range dataPoints {
 		if dataPoint.Latitude == 0 && dataPoint.Longitude == 0 {
 			continue // Skip points without valid coordinates
 		}
 
-		point := dataPointToGPXPoint(dataPoint)
+		point := dataPointToGPXPoint(profile, dataPoint)
 		result = append(result, point)
 	}
 	return result
 }
 
-func dataPointToGPXPoint(dataPoint data.DataPoint) gpx.GPXPoint {
-	gan := data.RSSIToGAN(dataPoint.RSSI)
+func dataPointToGPXPoint(profile data.GANProfile, dataPoint data.DataPoint) gpx.GPXPoint {
+	gan := profile.RSSIToGAN(dataPoint.RSSI)
 	result := gpx.GPXPoint{
 		Point: gpx.Point{
 			Latitude:  dataPoint.Latitude,
 			Longitude: dataPoint.Longitude,
 		},
 		Name:        fmt.Sprintf("%d/%x %ddBm", dataPoint.LAC, dataPoint.LAC, dataPoint.RSSI),
-		Description: fmt.Sprintf("LAC: %d\nCarrier: %x\nRSSI: %ddBm\nCx: %d\nGAN: %d", dataPoint.LAC, dataPoint.Carrier, dataPoint.RSSI, dataPoint.Cx, gan),
+		Description: fmt.Sprintf("LAC: %d\nCarrier: %x\nRSSI: %ddBm\nCx: %d\nGAN: %d\nServing: %t", dataPoint.LAC, dataPoint.Carrier, dataPoint.RSSI, dataPoint.Cx, gan, dataPoint.Serving),
 		Timestamp:   dataPoint.Timestamp,
 	}
+	if dataPoint.GPSIssue != data.NoGPSIssue {
+		result.Description += fmt.Sprintf("\nGPS issue: %s", dataPoint.GPSIssue)
+	}
 	result.Satellites.SetValue(dataPoint.Satellites)
+	if dataPoint.FixType == data.Fix3D || dataPoint.Altitude != 0 {
+		result.Elevation.SetValue(dataPoint.Altitude)
+	}
+	if dataPoint.HDOP > 0 {
+		result.HorizontalDilution.SetValue(dataPoint.HDOP)
+	}
 	return result
 }
+
+// WriteFieldReportsAsGPX writes the center of each field as waypoint.
+func WriteFieldReportsAsGPX(out io.Writer, name string, legend data.Legend, fieldReports []quality.FieldReport) error {
+	waypoints := make([]gpx.GPXPoint, 0, len(fieldReports))
+	for _, fieldReport := range fieldReports {
+		summary := fieldReport.Summary()
+		lat, lon := summary.Center()
+		if lat == 0 && lon == 0 {
+			continue // Skip fields without valid area
+		}
+
+		lacs := ""
+		for _, lac := range summary.LACs {
+			lacs += fmt.Sprintf("\nLAC %d: avg %ddBm, min %ddBm, max %ddBm", lac.LAC, lac.AverageRSSI, lac.MinRSSI, lac.MaxRSSI)
+		}
+		waypoints = append(waypoints, gpx.GPXPoint{
+			Point: gpx.Point{
+				Latitude:  lat,
+				Longitude: lon,
+			},
+			Name:        fmt.Sprintf("Field %s", summary.Field),
+			Description: fmt.Sprintf("Avg RSSI: %ddBm\nAvg GAN: %d\nAvg SLD: %ddB\nMeasurements: %d%s", summary.AverageRSSI, summary.AverageGAN, summary.AverageSLD, summary.Measurements, lacs),
+		})
+	}
+	result := gpx.GPX{
+		Version:     "1.1",
+		Creator:     "tetra-mess",
+		Name:        name,
+		Description: legend.String(),
+		Waypoints:   waypoints,
+	}
+
+	bytes, err := gpx.ToXml(&result, gpx.ToXmlParams{
+		Indent:  true,
+		Version: "1.1",
+	})
+	if err != nil {
+		return fmt.Errorf("error converting field reports to GPX XML: %w", err)
+	}
+
+	_, err = out.Write(bytes)
+	if err != nil {
+		return fmt.Errorf("error writing GPX XML to output: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,58 @@
+package gpx
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/tkrajina/gpxgo/gpx"
+
+	"github.com/ftl/tetra-mess/pkg/data"
+	"github.com/ftl/tetra-mess/pkg/handover"
+)
+
+func WriteHandoversAsGPX(out io.Writer, name string, legend data.Legend, events []handover.Event) error {
+	waypoints := make([]gpx.GPXPoint, 0, len(events))
+	for _, event := range events {
+		if event.Latitude == 0 && event.Longitude == 0 {
+			continue // Skip events without valid coordinates
+		}
+		waypoints = append(waypoints, handoverToGPXPoint(event))
+	}
+	result := gpx.GPX{
+		Version:     "1.1",
+		Creator:     "tetra-mess",
+		Name:        name,
+		Description: legend.String(),
+		Waypoints:   waypoints,
+	}
+
+	bytes, err := gpx.ToXml(&result, gpx.ToXmlParams{
+		Indent:  true,
+		Version: "1.1",
+	})
+	if err != nil {
+		return fmt.Errorf("error converting handovers to GPX XML: %w", err)
+	}
+
+	_, err = out.Write(bytes)
+	if err != nil {
+		return fmt.Errorf("error writing GPX XML to output: %w", err)
+	}
+
+	return nil
+}
+
+func handoverToGPXPoint(event handover.Event) gpx.GPXPoint {
+	result := gpx.GPXPoint{
+		Point: gpx.Point{
+			Latitude:  event.Latitude,
+			Longitude: event.Longitude,
+		},
+		Name:        fmt.Sprintf("%d → %d", event.OldLAC, event.NewLAC),
+		Description: fmt.Sprintf("Event: %s\nDevice: %s\nOld LAC: %d\nOld Carrier: %x\nNew LAC: %d\nNew Carrier: %x\nRSSI: %ddBm\nSLD: %ddB", event.Kind, event.Device, event.OldLAC, event.OldCarrier, event.NewLAC, event.NewCarrier, event.RSSI, event.SLD),
+		Timestamp:   event.Timestamp,
+		Type:        string(event.Kind),
+	}
+	result.Satellites.SetValue(event.Satellites)
+	return result
+}
@@ -0,0 +1,103 @@
+package gpx
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"time"
+
+	"github.com/tkrajina/gpxgo/gpx"
+
+	"github.com/ftl/tetra-mess/pkg/data"
+)
+
+// Track is a list of GPS positions, sorted by their timestamps, e.g. the log of an external GPS logger.
+type Track []data.Position
+
+// ReadTrack reads the points of all tracks in the given GPX document. Points without a timestamp are ignored.
+func ReadTrack(in io.Reader) (Track, error) {
+	document, err := gpx.Parse(in)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing GPX: %w", err)
+	}
+
+	var result Track
+	for _, track := range document.Tracks {
+		for _, segment := range track.Segments {
+			for _, point := range segment.Points {
+				if point.Timestamp.IsZero() {
+					continue
+				}
+				result = append(result, gpxPointToPosition(point))
+			}
+		}
+	}
+	return result.sorted(), nil
+}
+
+func gpxPointToPosition(point gpx.GPXPoint) data.Position {
+	fixType, _ := data.ParseFixType(point.TypeOfGpsFix)
+	return data.Position{
+		Latitude:   point.Latitude,
+		Longitude:  point.Longitude,
+		Satellites: point.Satellites.Value(),
+		Timestamp:  point.Timestamp,
+		Altitude:   point.Elevation.Value(),
+		FixType:    fixType,
+		HDOP:       point.HorizontalDilution.Value(),
+	}
+}
+
+// Join returns a new track that contains the positions of this and the other track.
+func (t Track) Join(other Track) Track {
+	return slices.Concat(t, other).sorted()
+}
+
+func (t Track) sorted() Track {
+	slices.SortStableFunc(t, func(a, b data.Position) int {
+		return a.Timestamp.Compare(b.Timestamp)
+	})
+	return t
+}
+
+// PositionAt returns the position at the given time, interpolated linearly between the two enclosing positions of
+// the track. If the time is outside of the track or the enclosing positions are more than maxGap apart, no position
+// is available.
+func (t Track) PositionAt(timestamp time.Time, maxGap time.Duration) (data.Position, bool) {
+	i, found := slices.BinarySearchFunc(t, timestamp, func(p data.Position, timestamp time.Time) int {
+		return p.Timestamp.Compare(timestamp)
+	})
+	if found {
+		return t[i], true
+	}
+	if i == 0 || i == len(t) {
+		return data.NoPosition, false
+	}
+
+	previous := t[i-1]
+	next := t[i]
+	gap := next.Timestamp.Sub(previous.Timestamp)
+	if gap > maxGap {
+		return data.NoPosition, false
+	}
+
+	return data.InterpolatePosition(previous, next, timestamp), true
+}
+
+// Geotag sets the position of the given data point from the track if the data point has no GPS fix of its own.
+// The offset is added to the timestamp of the data point to match the clock of the track. The result tells if
+// the data point was geotagged.
+func (t Track) Geotag(dataPoint data.DataPoint, offset time.Duration, maxGap time.Duration) (data.DataPoint, bool) {
+	if dataPoint.Position().HasFix() {
+		return dataPoint, false
+	}
+	position, ok := t.PositionAt(dataPoint.Timestamp.Add(offset), maxGap)
+	if !ok {
+		return dataPoint, false
+	}
+
+	// a position from the track is a fix, even if the track does not tell the number of satellites
+	position.Satellites = max(position.Satellites, 1)
+
+	return dataPoint.WithPosition(position), true
+}
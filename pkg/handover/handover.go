@@ -0,0 +1,134 @@
+package handover
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/ftl/tetra-mess/pkg/data"
+	"github.com/ftl/tetra-mess/pkg/quality"
+)
+
+type Kind string
+
+const (
+	// Handover means the radio changed the cell it is registered to.
+	Handover Kind = "handover"
+	// BestServerChange means the strongest received cell changed.
+	BestServerChange Kind = "best-server"
+)
+
+type Event struct {
+	Kind       Kind      `json:"kind"`
+	Device     string    `json:"device,omitempty"`
+	Timestamp  time.Time `json:"ts"`
+	Latitude   float64   `json:"lat"`
+	Longitude  float64   `json:"lon"`
+	Satellites int       `json:"sats"`
+	OldLAC     uint32    `json:"old_lac"`
+	OldCarrier uint32    `json:"old_carrier"`
+	NewLAC     uint32    `json:"new_lac"`
+	NewCarrier uint32    `json:"new_carrier"`
+	RSSI       int       `json:"rssi"`
+	SLD        int       `json:"sld"`
+}
+
+func (e Event) String() string {
+	return fmt.Sprintf("%s from LAC %d to LAC %d", e.Kind, e.OldLAC, e.NewLAC)
+}
+
+// Detector detects changes of the serving cell and the best server between consecutive measurements of each device.
+type Detector struct {
+	devices map[string]*deviceState
+}
+
+type deviceState struct {
+	serving    data.DataPoint
+	bestServer data.DataPoint
+}
+
+func NewDetector() *Detector {
+	return &Detector{
+		devices: make(map[string]*deviceState),
+	}
+}
+
+// Add the next measurement and return the events between the previous and this measurement.
+func (d *Detector) Add(measurement quality.Measurement) []Event {
+	bestServer := measurement.BestServer()
+	if bestServer.IsZero() || bestServer.RSSI == data.NoSignal {
+		return nil
+	}
+
+	state, ok := d.devices[bestServer.Device]
+	if !ok {
+		state = &deviceState{}
+		d.devices[bestServer.Device] = state
+	}
+
+	var result []Event
+	sld := measurement.SignalLevelDifference()
+
+	serving := measurement.ServingCell()
+	if !serving.IsZero() {
+		if !state.serving.IsZero() && state.serving.LAC != serving.LAC {
+			result = append(result, newEvent(Handover, state.serving, serving, sld))
+		}
+		state.serving = serving
+	}
+
+	if !state.bestServer.IsZero() && state.bestServer.LAC != bestServer.LAC {
+		result = append(result, newEvent(BestServerChange, state.bestServer, bestServer, sld))
+	}
+	state.bestServer = bestServer
+
+	return result
+}
+
+func newEvent(kind Kind, old data.DataPoint, current data.DataPoint, sld int) Event {
+	return Event{
+		Kind:       kind,
+		Device:     current.Device,
+		Timestamp:  current.Timestamp,
+		Latitude:   current.Latitude,
+		Longitude:  current.Longitude,
+		Satellites: current.Satellites,
+		OldLAC:     old.LAC,
+		OldCarrier: old.Carrier,
+		NewLAC:     current.LAC,
+		NewCarrier: current.Carrier,
+		RSSI:       current.RSSI,
+		SLD:        sld,
+	}
+}
+
+// Filename returns the name of the event file that belongs to the given trace file.
+func Filename(traceFilename string) string {
+	base, _ := data.SplitFilename(traceFilename)
+	return base + "-handovers.json"
+}
+
+func EventToJSON(event Event) string {
+	encoded, _ := json.Marshal(event)
+	return string(encoded)
+}
+
+func ReadEvents(in io.Reader) ([]Event, error) {
+	lines, err := data.ReadLines(in)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]Event, 0, len(lines))
+	for i, line := range lines {
+		var event Event
+		err := json.NewDecoder(strings.NewReader(line)).Decode(&event)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing line %d: %w", i+1, err)
+		}
+		result = append(result, event)
+	}
+	return result, nil
+}
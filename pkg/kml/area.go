@@ -0,0 +1,87 @@
+package kml
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/ftl/tetra-mess/pkg/data"
+)
+
+// ReadAreas reads all polygons of the given KML document, e.g. the boundary of a municipality.
+func ReadAreas(in io.Reader) (data.Areas, error) {
+	decoder := xml.NewDecoder(in)
+
+	var result data.Areas
+	var polygon *data.Polygon
+	inner := false
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error parsing KML: %w", err)
+		}
+
+		switch element := token.(type) {
+		case xml.StartElement:
+			switch element.Name.Local {
+			case "Polygon":
+				polygon = &data.Polygon{}
+			case "outerBoundaryIs":
+				inner = false
+			case "innerBoundaryIs":
+				inner = true
+			case "coordinates":
+				if polygon == nil {
+					continue // coordinates of points and lines
+				}
+				var text string
+				err := decoder.DecodeElement(&text, &element)
+				if err != nil {
+					return nil, fmt.Errorf("error parsing KML coordinates: %w", err)
+				}
+				ring, err := parseKMLCoordinates(text)
+				if err != nil {
+					return nil, err
+				}
+				if inner {
+					polygon.Holes = append(polygon.Holes, ring)
+				} else {
+					polygon.Outer = ring
+				}
+			}
+		case xml.EndElement:
+			if element.Name.Local == "Polygon" && polygon != nil {
+				result = append(result, *polygon)
+				polygon = nil
+			}
+		}
+	}
+	return result, nil
+}
+
+// parseKMLCoordinates parses coordinate tuples of the form lon,lat[,alt] separated by whitespace.
+func parseKMLCoordinates(text string) ([]data.LatLon, error) {
+	tuples := strings.Fields(text)
+	result := make([]data.LatLon, 0, len(tuples))
+	for _, tuple := range tuples {
+		values := strings.Split(tuple, ",")
+		if len(values) < 2 {
+			return nil, fmt.Errorf("invalid KML coordinates: %s", tuple)
+		}
+		lon, err := strconv.ParseFloat(values[0], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid KML coordinates: %s", tuple)
+		}
+		lat, err := strconv.ParseFloat(values[1], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid KML coordinates: %s", tuple)
+		}
+		result = append(result, data.LatLon{Latitude: lat, Longitude: lon})
+	}
+	return result, nil
+}
@@ -0,0 +1,50 @@
+package kml
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/twpayne/go-kml/v3"
+
+	"github.com/ftl/tetra-mess/pkg/data"
+	"github.com/ftl/tetra-mess/pkg/handover"
+)
+
+func WriteHandoversAsKML(out io.Writer, name string, legend data.Legend, events []handover.Event) error {
+	elements := make([]kml.Element, 0, len(events)+3)
+	elements = append(elements, documentHeader(name, legend)...)
+	for _, event := range events {
+		if event.Latitude == 0 && event.Longitude == 0 {
+			continue // Skip events without valid coordinates
+		}
+		elements = append(elements, handoverToKMLPlacemark(legend, event))
+	}
+
+	doc := kml.KML(
+		kml.Document(elements...),
+	)
+
+	return doc.WriteIndent(out, "", "  ")
+}
+
+func handoverToKMLPlacemark(legend data.Legend, event handover.Event) kml.Element {
+	color := legend.Color(event.RSSI)
+	return kml.Placemark(
+		kml.Name(fmt.Sprintf("%d → %d", event.OldLAC, event.NewLAC)),
+		kml.Description(fmt.Sprintf("Event: %s<br/>Device: %s<br/>Old LAC: %d<br/>Old Carrier: %x<br/>New LAC: %d<br/>New Carrier: %x<br/>RSSI: %ddBm<br/>SLD: %ddB", event.Kind, event.Device, event.OldLAC, event.OldCarrier, event.NewLAC, event.NewCarrier, event.RSSI, event.SLD)),
+		kml.TimeStamp(kml.When(event.Timestamp)),
+		kml.Point(
+			kml.Coordinates(kml.Coordinate{Lat: event.Latitude, Lon: event.Longitude}),
+		),
+		kml.Style(
+			kml.IconStyle(
+				// https://kml4earth.appspot.com/icons.html
+				kml.Icon(kml.Href("http://maps.google.com/mapfiles/kml/shapes/triangle.png")),
+				kml.Color(color),
+			),
+			kml.LabelStyle(
+				kml.Color(color),
+			),
+		),
+	)
+}
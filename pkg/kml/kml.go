@@ -2,6 +2,7 @@ package kml
 
 import (
 	"fmt"
+	"image/color"
 	"io"
 
 	"github.com/twpayne/go-kml/v3"
@@ -10,12 +11,10 @@ import (
 	"github.com/ftl/tetra-mess/pkg/quality"
 )
 
-func WriteDataPointsAsKML(out io.Writer, name string, dataPoints []data.DataPoint) error {
-	elements := make([]kml.Element, 0, len(dataPoints)+1)
-	elements = append(elements,
-		kml.Name(name),
-	)
-	elements = append(elements, dataPointsToKMLPlacemarks(dataPoints)...)
+func WriteDataPointsAsKML(out io.Writer, name string, legend data.Legend, dataPoints []data.DataPoint) error {
+	elements := make([]kml.Element, 0, len(dataPoints)+3)
+	elements = append(elements, documentHeader(name, legend)...)
+	elements = append(elements, dataPointsToKMLPlacemarks(legend, dataPoints)...)
 
 	doc := kml.KML(
 		kml.Document(elements...),
@@ -24,58 +23,69 @@ func WriteDataPointsAsKML(out io.Writer, name string, dataPoints []data.DataPoin
 	return doc.WriteIndent(out, "", "  ")
 }
 
-func dataPointsToKMLPlacemarks(dataPoints []data.DataPoint) []kml.Element {
+func dataPointsToKMLPlacemarks(legend data.Legend, dataPoints []data.DataPoint) []kml.Element {
 	result := make([]kml.Element, 0, len(dataPoints))
 	for _, dataPoint := range dataPoints {
 		if dataPoint.Latitude == 0 && dataPoint.Longitude == 0 {
 			continue // Skip points without valid coordinates
 		}
 
-		point := dataPointToKMLPlacemark(dataPoint)
+		point := dataPointToKMLPlacemark(legend, dataPoint)
 		result = append(result, point)
 	}
 	return result
 }
 
-func dataPointToKMLPlacemark(dataPoint data.DataPoint) kml.Element {
-	gan := data.RSSIToGAN(dataPoint.RSSI)
-	color := data.GANToColor(gan)
+func dataPointToKMLPlacemark(legend data.Legend, dataPoint data.DataPoint) kml.Element {
+	// https://kml4earth.appspot.com/icons.html
+	style := dataPointStyle("http://maps.google.com/mapfiles/kml/shapes/placemark_circle.png", legend.Color(dataPoint.RSSI))
+	return dataPointPlacemark(legend.Profile, dataPoint, style)
+}
+
+// dataPointPlacemark creates the placemark of the given data point with the given style, either an inline style or a
+// style URL.
+func dataPointPlacemark(profile data.GANProfile, dataPoint data.DataPoint, style kml.Element) kml.Element {
+	gan := profile.RSSIToGAN(dataPoint.RSSI)
+	description := fmt.Sprintf("LAC: %d<br/>Carrier: %x<br/>RSSI: %ddBm<br/>Cx: %d<br/>GAN: %d<br/>Serving: %t", dataPoint.LAC, dataPoint.Carrier, dataPoint.RSSI, dataPoint.Cx, gan, dataPoint.Serving)
+	if dataPoint.GPSIssue != data.NoGPSIssue {
+		description += fmt.Sprintf("<br/>GPS issue: %s", dataPoint.GPSIssue)
+	}
 	return kml.Placemark(
 		kml.Name(fmt.Sprintf("%d/%x %ddBm", dataPoint.LAC, dataPoint.LAC, dataPoint.RSSI)),
-		kml.Description(fmt.Sprintf("LAC: %d<br/>Carrier: %x<br/>RSSI: %ddBm<br/>Cx: %d<br/>GAN: %d", dataPoint.LAC, dataPoint.Carrier, dataPoint.RSSI, dataPoint.Cx, gan)),
+		kml.Description(description),
 		kml.TimeStamp(kml.When(dataPoint.Timestamp)),
 		kml.Point(
 			kml.Coordinates(kml.Coordinate{Lat: dataPoint.Latitude, Lon: dataPoint.Longitude}),
 		),
-		kml.Style(
-			kml.IconStyle(
-				// https://kml4earth.appspot.com/icons.html
-				kml.Icon(kml.Href("http://maps.google.com/mapfiles/kml/shapes/placemark_circle.png")),
-				kml.Color(color),
-			),
-			kml.LabelStyle(
-				kml.Color(color),
-			),
-		),
+		style,
 	)
 }
 
-func WriteFieldReportsAsKML(out io.Writer, name string, fieldReports []quality.FieldReport) error {
-	elements := make([]kml.Element, 0, len(fieldReports)+9)
-	elements = append(elements,
-		kml.Name(name),
+func dataPointStyle(iconHref string, color color.Color) *kml.StyleElement {
+	return kml.Style(
+		kml.IconStyle(
+			kml.Icon(kml.Href(iconHref)),
+			kml.Color(color),
+		),
+		kml.LabelStyle(
+			kml.Color(color),
+		),
 	)
-	for gan := data.NoGAN; gan <= 4; gan++ {
-		styleID := fmt.Sprintf("gan%d-style", gan)
-		style := kml.Style(
-			kml.PolyStyle(
-				kml.Color(data.GANToColor(gan)),
-				kml.Fill(true),
-			),
-		).WithID(styleID)
-		elements = append(elements, style)
+}
+
+func WriteFieldReportsAsKML(out io.Writer, name string, legend data.Legend, fieldReports []quality.FieldReport) error {
+	elements := make([]kml.Element, 0, len(fieldReports)+11)
+	elements = append(elements, documentHeader(name, legend)...)
+	if !legend.Colors.Gradient {
+		for gan := data.NoGAN; gan <= 4; gan++ {
+			styleID := fmt.Sprintf("gan%d-style", gan)
+			style := kml.Style(
+				fieldPolyStyle(legend.GANColor(gan)),
+			).WithID(styleID)
+			elements = append(elements, style)
+		}
 	}
-	elements = append(elements, fieldReportsToKMLPlacemarks(fieldReports)...)
+	elements = append(elements, fieldReportsToKMLPlacemarks(legend, fieldReports)...)
 
 	doc := kml.KML(
 		kml.Document(elements...),
@@ -84,19 +94,32 @@ func WriteFieldReportsAsKML(out io.Writer, name string, fieldReports []quality.F
 	return doc.WriteIndent(out, "", "  ")
 }
 
-func fieldReportsToKMLPlacemarks(fieldReports []quality.FieldReport) []kml.Element {
+func fieldPolyStyle(fill color.Color) kml.Element {
+	return kml.PolyStyle(
+		kml.Color(fill),
+		kml.Fill(true),
+	)
+}
+
+// fieldReportsToKMLPlacemarks uses the shared GAN level styles for discrete color schemes. With a gradient, each field
+// gets its own style with the color of its average RSSI.
+func fieldReportsToKMLPlacemarks(legend data.Legend, fieldReports []quality.FieldReport) []kml.Element {
 	result := make([]kml.Element, 0, len(fieldReports))
 	for _, fieldStat := range fieldReports {
 		minLat, minLon, maxLat, maxLon := fieldStat.Area()
 		if minLat == 0 && minLon == 0 && maxLat == 0 && maxLon == 0 {
 			continue // Skip fields without valid area
 		}
-		avgGAN := data.RSSIToGAN(fieldStat.AverageRSSI())
-		styleURL := fmt.Sprintf("#gan%d-style", avgGAN)
+		var style kml.Element
+		if legend.Colors.Gradient {
+			style = kml.Style(fieldPolyStyle(legend.Color(fieldStat.AverageRSSI())))
+		} else {
+			style = kml.StyleURL(fmt.Sprintf("#gan%d-style", fieldStat.AverageGAN()))
+		}
 		placemark := kml.Placemark(
 			kml.Name(fmt.Sprintf("Field %s", fieldStat.Field.FieldID())),
 			kml.Description(fieldReportDescription(fieldStat)),
-			kml.StyleURL(styleURL),
+			style,
 			kml.Polygon(
 				kml.OuterBoundaryIs(
 					kml.LinearRing(
@@ -0,0 +1,169 @@
+package kml
+
+import (
+	"archive/zip"
+	"cmp"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"slices"
+	"time"
+
+	"github.com/twpayne/go-kml/v3"
+
+	"github.com/ftl/tetra-mess/pkg/data"
+)
+
+const (
+	kmzDocumentFilename = "doc.kml"
+	kmzLegendFilename   = "files/legend.png"
+	kmzIconFilename     = "files/circle.png"
+)
+
+// WriteDataPointsAsKMZ writes the data points as KMZ archive. In contrast to WriteDataPointsAsKML, the placemarks use
+// shared styles and are grouped into one folder per LAC and GAN level, so they can be toggled in Google Earth. The
+// icon and the legend image are bundled into the archive.
+func WriteDataPointsAsKMZ(out io.Writer, name string, legend data.Legend, dataPoints []data.DataPoint) error {
+	legend.ImageHref = kmzLegendFilename
+
+	elements := make([]kml.Element, 0, 20)
+	elements = append(elements, documentHeader(name, legend)...)
+	elements = append(elements, dataPointStyles(legend, dataPoints)...)
+	elements = append(elements, dataPointFolders(legend, dataPoints)...)
+	doc := kml.KML(
+		kml.Document(elements...),
+	)
+
+	archive := zip.NewWriter(out)
+	// Google Earth expects the document to be the first file in the archive
+	err := writeKMZFile(archive, kmzDocumentFilename, func(w io.Writer) error {
+		return doc.WriteIndent(w, "", "  ")
+	})
+	if err != nil {
+		return err
+	}
+	err = writeKMZFile(archive, kmzLegendFilename, func(w io.Writer) error {
+		return WriteLegendPNG(w, legend)
+	})
+	if err != nil {
+		return err
+	}
+	err = writeKMZFile(archive, kmzIconFilename, writeCircleIconPNG)
+	if err != nil {
+		return err
+	}
+	return archive.Close()
+}
+
+func writeKMZFile(archive *zip.Writer, filename string, write func(io.Writer) error) error {
+	w, err := archive.CreateHeader(&zip.FileHeader{
+		Name:     filename,
+		Method:   zip.Deflate,
+		Modified: time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("cannot create %s in KMZ archive: %w", filename, err)
+	}
+	err = write(w)
+	if err != nil {
+		return fmt.Errorf("cannot write %s to KMZ archive: %w", filename, err)
+	}
+	return nil
+}
+
+// dataPointStyleID returns the ID of the shared style for the given RSSI. Discrete color schemes use one style per
+// GAN level, gradients one style per RSSI value.
+func dataPointStyleID(legend data.Legend, rssi int) string {
+	if legend.Colors.Gradient && rssi != data.NoSignal {
+		return fmt.Sprintf("rssi%d-style", rssi)
+	}
+	return fmt.Sprintf("gan%d-style", legend.Profile.RSSIToGAN(rssi))
+}
+
+// dataPointStyles returns the shared styles that are used by the given data points.
+func dataPointStyles(legend data.Legend, dataPoints []data.DataPoint) []kml.Element {
+	var result []kml.Element
+	styleIDs := make(map[string]bool)
+	for _, dataPoint := range dataPoints {
+		if dataPoint.Latitude == 0 && dataPoint.Longitude == 0 {
+			continue // Skip points without valid coordinates
+		}
+		styleID := dataPointStyleID(legend, dataPoint.RSSI)
+		if styleIDs[styleID] {
+			continue
+		}
+		styleIDs[styleID] = true
+		result = append(result, dataPointStyle(kmzIconFilename, legend.Color(dataPoint.RSSI)).WithID(styleID))
+	}
+	return result
+}
+
+// dataPointFolders groups the data points into one folder per LAC, and within each LAC into one folder per GAN level.
+func dataPointFolders(legend data.Legend, dataPoints []data.DataPoint) []kml.Element {
+	byLAC := make(map[uint32][]data.DataPoint)
+	for _, dataPoint := range dataPoints {
+		if dataPoint.Latitude == 0 && dataPoint.Longitude == 0 {
+			continue // Skip points without valid coordinates
+		}
+		byLAC[dataPoint.LAC] = append(byLAC[dataPoint.LAC], dataPoint)
+	}
+	lacs := make([]uint32, 0, len(byLAC))
+	for lac := range byLAC {
+		lacs = append(lacs, lac)
+	}
+	slices.SortFunc(lacs, cmp.Compare)
+
+	result := make([]kml.Element, 0, len(lacs))
+	for _, lac := range lacs {
+		byGAN := make(map[int][]kml.Element)
+		for _, dataPoint := range byLAC[lac] {
+			gan := legend.Profile.RSSIToGAN(dataPoint.RSSI)
+			style := kml.StyleURL("#" + dataPointStyleID(legend, dataPoint.RSSI))
+			byGAN[gan] = append(byGAN[gan], dataPointPlacemark(legend.Profile, dataPoint, style))
+		}
+
+		lacElements := []kml.Element{
+			kml.Name(fmt.Sprintf("LAC %d/%x", lac, lac)),
+			kml.Open(false),
+		}
+		for _, entry := range legend.Entries() {
+			placemarks := byGAN[entry.GAN]
+			if len(placemarks) == 0 {
+				continue
+			}
+			ganElements := make([]kml.Element, 0, len(placemarks)+1)
+			ganElements = append(ganElements, kml.Name(entry.Label))
+			ganElements = append(ganElements, placemarks...)
+			lacElements = append(lacElements, kml.Folder(ganElements...))
+		}
+		result = append(result, kml.Folder(lacElements...))
+	}
+	return result
+}
+
+const circleIconSize = 32
+
+// writeCircleIconPNG draws a white circle with a dark outline. Google Earth tints the white area with the color of
+// the IconStyle.
+func writeCircleIconPNG(out io.Writer) error {
+	img := image.NewNRGBA(image.Rect(0, 0, circleIconSize, circleIconSize))
+	center := float64(circleIconSize-1) / 2
+	outer := float64(circleIconSize)/2 - 1
+	inner := outer - 3
+	for y := range circleIconSize {
+		for x := range circleIconSize {
+			dx := float64(x) - center
+			dy := float64(y) - center
+			distance := dx*dx + dy*dy
+			switch {
+			case distance <= inner*inner:
+				img.SetNRGBA(x, y, color.NRGBA{R: 255, G: 255, B: 255, A: 255})
+			case distance <= outer*outer:
+				img.SetNRGBA(x, y, color.NRGBA{R: 40, G: 40, B: 40, A: 255})
+			}
+		}
+	}
+	return png.Encode(out, img)
+}
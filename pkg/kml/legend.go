@@ -0,0 +1,158 @@
+package kml
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"strings"
+
+	"github.com/twpayne/go-kml/v3"
+
+	"github.com/ftl/tetra-mess/pkg/data"
+)
+
+// legendScreenOverlay shows the legend image in the lower left corner of the map. Without image, there is no overlay.
+func legendScreenOverlay(legend data.Legend) kml.Element {
+	if legend.ImageHref == "" {
+		return nil
+	}
+	return kml.ScreenOverlay(
+		kml.Name("Legend"),
+		kml.Description(legend.String()),
+		kml.Icon(kml.Href(legend.ImageHref)),
+		kml.OverlayXY(kml.Vec2{X: 0, Y: 0, XUnits: kml.UnitsFraction, YUnits: kml.UnitsFraction}),
+		kml.ScreenXY(kml.Vec2{X: 10, Y: 30, XUnits: kml.UnitsPixels, YUnits: kml.UnitsPixels}),
+		kml.Size(kml.Vec2{X: 0, Y: 0, XUnits: kml.UnitsPixels, YUnits: kml.UnitsPixels}),
+	)
+}
+
+// documentHeader returns the name, the description and the legend of a KML document.
+func documentHeader(name string, legend data.Legend) []kml.Element {
+	result := []kml.Element{
+		kml.Name(name),
+		kml.Description(legend.String()),
+	}
+	if overlay := legendScreenOverlay(legend); overlay != nil {
+		result = append(result, overlay)
+	}
+	return result
+}
+
+const (
+	legendScale      = 2
+	legendPadding    = 8 * legendScale
+	legendSwatchSize = 10 * legendScale
+	legendLineHeight = (glyphHeight + 5) * legendScale
+)
+
+var (
+	legendBackground = color.NRGBA{R: 255, G: 255, B: 255, A: 230}
+	legendForeground = color.RGBA{R: 0, G: 0, B: 0, A: 255}
+)
+
+// WriteLegendPNG draws the legend as PNG image, to be used as ScreenOverlay in a KML document.
+func WriteLegendPNG(out io.Writer, legend data.Legend) error {
+	title := strings.ToUpper(legend.Title())
+	entries := legend.Entries()
+
+	contentWidth := textWidth(title)
+	for _, entry := range entries {
+		contentWidth = max(contentWidth, legendSwatchSize+legendPadding/2+textWidth(strings.ToUpper(entry.Label)))
+	}
+	width := 2*legendPadding + contentWidth
+	height := 2*legendPadding + (len(entries)+1)*legendLineHeight
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(img, img.Bounds(), image.NewUniform(legendBackground), image.Point{}, draw.Src)
+
+	y := legendPadding
+	drawText(img, legendPadding, y, title)
+	y += legendLineHeight
+	for _, entry := range entries {
+		swatch := image.Rect(legendPadding, y, legendPadding+legendSwatchSize, y+glyphHeight*legendScale)
+		draw.Draw(img, swatch, image.NewUniform(entry.Color), image.Point{}, draw.Src)
+		drawText(img, legendPadding+legendSwatchSize+legendPadding/2, y, strings.ToUpper(entry.Label))
+		y += legendLineHeight
+	}
+
+	return png.Encode(out, img)
+}
+
+func textWidth(text string) int {
+	return len([]rune(text)) * (glyphWidth + 1) * legendScale
+}
+
+func drawText(img *image.RGBA, x, y int, text string) {
+	for _, r := range text {
+		glyph := glyphs[r]
+		for row, bits := range glyph {
+			for col := range glyphWidth {
+				if bits&(1<<(glyphWidth-1-col)) == 0 {
+					continue
+				}
+				dot := image.Rect(x+col*legendScale, y+row*legendScale, x+(col+1)*legendScale, y+(row+1)*legendScale)
+				draw.Draw(img, dot, image.NewUniform(legendForeground), image.Point{}, draw.Src)
+			}
+		}
+		x += (glyphWidth + 1) * legendScale
+	}
+}
+
+const (
+	glyphWidth  = 5
+	glyphHeight = 7
+)
+
+// glyphs is a minimal 5x7 bitmap font for the legend image. It contains only upper case letters, digits, and the
+// symbols used in the legend. Unknown characters are drawn as space.
+var glyphs = map[rune][glyphHeight]uint8{
+	'0': {0x0e, 0x11, 0x13, 0x15, 0x19, 0x11, 0x0e},
+	'1': {0x04, 0x0c, 0x04, 0x04, 0x04, 0x04, 0x0e},
+	'2': {0x0e, 0x11, 0x01, 0x02, 0x04, 0x08, 0x1f},
+	'3': {0x1f, 0x02, 0x04, 0x02, 0x01, 0x11, 0x0e},
+	'4': {0x02, 0x06, 0x0a, 0x12, 0x1f, 0x02, 0x02},
+	'5': {0x1f, 0x10, 0x1e, 0x01, 0x01, 0x11, 0x0e},
+	'6': {0x06, 0x08, 0x10, 0x1e, 0x11, 0x11, 0x0e},
+	'7': {0x1f, 0x01, 0x02, 0x04, 0x08, 0x08, 0x08},
+	'8': {0x0e, 0x11, 0x11, 0x0e, 0x11, 0x11, 0x0e},
+	'9': {0x0e, 0x11, 0x11, 0x0f, 0x01, 0x02, 0x0c},
+	'A': {0x0e, 0x11, 0x11, 0x11, 0x1f, 0x11, 0x11},
+	'B': {0x1e, 0x11, 0x11, 0x1e, 0x11, 0x11, 0x1e},
+	'C': {0x0e, 0x11, 0x10, 0x10, 0x10, 0x11, 0x0e},
+	'D': {0x1c, 0x12, 0x11, 0x11, 0x11, 0x12, 0x1c},
+	'E': {0x1f, 0x10, 0x10, 0x1e, 0x10, 0x10, 0x1f},
+	'F': {0x1f, 0x10, 0x10, 0x1e, 0x10, 0x10, 0x10},
+	'G': {0x0e, 0x11, 0x10, 0x17, 0x11, 0x11, 0x0f},
+	'H': {0x11, 0x11, 0x11, 0x1f, 0x11, 0x11, 0x11},
+	'I': {0x0e, 0x04, 0x04, 0x04, 0x04, 0x04, 0x0e},
+	'J': {0x07, 0x02, 0x02, 0x02, 0x02, 0x12, 0x0c},
+	'K': {0x11, 0x12, 0x14, 0x18, 0x14, 0x12, 0x11},
+	'L': {0x10, 0x10, 0x10, 0x10, 0x10, 0x10, 0x1f},
+	'M': {0x11, 0x1b, 0x15, 0x15, 0x11, 0x11, 0x11},
+	'N': {0x11, 0x11, 0x19, 0x15, 0x13, 0x11, 0x11},
+	'O': {0x0e, 0x11, 0x11, 0x11, 0x11, 0x11, 0x0e},
+	'P': {0x1e, 0x11, 0x11, 0x1e, 0x10, 0x10, 0x10},
+	'Q': {0x0e, 0x11, 0x11, 0x11, 0x15, 0x12, 0x0d},
+	'R': {0x1e, 0x11, 0x11, 0x1e, 0x14, 0x12, 0x11},
+	'S': {0x0f, 0x10, 0x10, 0x0e, 0x01, 0x01, 0x1e},
+	'T': {0x1f, 0x04, 0x04, 0x04, 0x04, 0x04, 0x04},
+	'U': {0x11, 0x11, 0x11, 0x11, 0x11, 0x11, 0x0e},
+	'V': {0x11, 0x11, 0x11, 0x11, 0x11, 0x0a, 0x04},
+	'W': {0x11, 0x11, 0x11, 0x15, 0x15, 0x15, 0x0a},
+	'X': {0x11, 0x11, 0x0a, 0x04, 0x0a, 0x11, 0x11},
+	'Y': {0x11, 0x11, 0x11, 0x0a, 0x04, 0x04, 0x04},
+	'Z': {0x1f, 0x01, 0x02, 0x04, 0x08, 0x10, 0x1f},
+	'-': {0x00, 0x00, 0x00, 0x1f, 0x00, 0x00, 0x00},
+	':': {0x00, 0x0c, 0x0c, 0x00, 0x0c, 0x0c, 0x00},
+	'<': {0x02, 0x04, 0x08, 0x10, 0x08, 0x04, 0x02},
+	'>': {0x08, 0x04, 0x02, 0x01, 0x02, 0x04, 0x08},
+	'=': {0x00, 0x00, 0x1f, 0x00, 0x1f, 0x00, 0x00},
+	'.': {0x00, 0x00, 0x00, 0x00, 0x00, 0x0c, 0x0c},
+	',': {0x00, 0x00, 0x00, 0x00, 0x0c, 0x04, 0x08},
+	'(': {0x02, 0x04, 0x08, 0x08, 0x08, 0x04, 0x02},
+	')': {0x08, 0x04, 0x02, 0x02, 0x02, 0x04, 0x08},
+	'/': {0x00, 0x01, 0x02, 0x04, 0x08, 0x10, 0x00},
+	'_': {0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x1f},
+}
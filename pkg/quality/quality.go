@@ -7,15 +7,22 @@ import (
 )
 
 type QualityReport struct {
+	profile     data.GANProfile
 	fieldsByUTM map[string]*FieldReport
 }
 
-func NewQualityReport() *QualityReport {
+// NewQualityReport creates a new quality report that uses the given GAN profile to rate the signal quality.
+func NewQualityReport(profile data.GANProfile) *QualityReport {
 	return &QualityReport{
+		profile:     profile,
 		fieldsByUTM: make(map[string]*FieldReport),
 	}
 }
 
+func (a *QualityReport) Profile() data.GANProfile {
+	return a.profile
+}
+
 func (a *QualityReport) AddMeasurement(measurement Measurement) {
 	for _, dataPoint := range measurement.DataPoints {
 		a.Add(dataPoint)
@@ -27,7 +34,7 @@ func (a *QualityReport) Add(dataPoint data.DataPoint) {
 
 	field, ok := a.fieldsByUTM[fieldID]
 	if !ok {
-		field = NewFieldReport(dataPoint.UTMField())
+		field = NewFieldReport(dataPoint.UTMField(), a.profile)
 		a.fieldsByUTM[fieldID] = field
 	}
 	field.Add(dataPoint)
@@ -44,20 +51,22 @@ func (a *QualityReport) FieldReports() []FieldReport {
 func (a *QualityReport) FieldReportByUTM(utmField data.UTMField) FieldReport {
 	result, ok := a.fieldsByUTM[utmField.FieldID()]
 	if !ok {
-		return FieldReport{Field: utmField}
+		return FieldReport{Field: utmField, Profile: a.profile}
 	}
 	return *result
 }
 
 type FieldReport struct {
 	Field        data.UTMField
+	Profile      data.GANProfile
 	LACs         map[uint32]*LACReport
 	Measurements map[string]*Measurement
 }
 
-func NewFieldReport(field data.UTMField) *FieldReport {
+func NewFieldReport(field data.UTMField, profile data.GANProfile) *FieldReport {
 	return &FieldReport{
 		Field:        field,
+		Profile:      profile,
 		LACs:         make(map[uint32]*LACReport),
 		Measurements: make(map[string]*Measurement),
 	}
@@ -70,7 +79,7 @@ func (f *FieldReport) Area() (minLat float64, minLon float64, maxLat float64, ma
 func (f *FieldReport) Add(dataPoint data.DataPoint) {
 	lacStats, ok := f.LACs[dataPoint.LAC]
 	if !ok {
-		lacStats = &LACReport{LAC: dataPoint.LAC}
+		lacStats = &LACReport{LAC: dataPoint.LAC, Profile: f.Profile}
 		f.LACs[dataPoint.LAC] = lacStats
 	}
 	lacStats.Add(dataPoint)
@@ -135,7 +144,7 @@ func (f *FieldReport) AverageGAN() int {
 	if avgRSSI == data.NoSignal {
 		return data.NoGAN
 	}
-	return data.RSSIToGAN(avgRSSI)
+	return f.Profile.RSSIToGAN(avgRSSI)
 }
 
 func (f *FieldReport) AverageSignalLevelDifference() int {
@@ -156,6 +165,7 @@ func (f *FieldReport) AverageSignalLevelDifference() int {
 
 type LACReport struct {
 	LAC     uint32
+	Profile data.GANProfile
 	MinRSSI int
 	MaxRSSI int
 
@@ -198,7 +208,7 @@ func (s *LACReport) CurrentGAN() int {
 	if currentRSSI == data.NoSignal {
 		return data.NoGAN
 	}
-	return data.RSSIToGAN(currentRSSI)
+	return s.Profile.RSSIToGAN(currentRSSI)
 }
 
 func (s *LACReport) AverageRSSI() int {
@@ -218,7 +228,7 @@ func (s *LACReport) AverageGAN() int {
 	if avgRSSI == data.NoSignal {
 		return data.NoGAN
 	}
-	return data.RSSIToGAN(avgRSSI)
+	return s.Profile.RSSIToGAN(avgRSSI)
 }
 
 type Measurement struct {
@@ -253,6 +263,15 @@ func (m *Measurement) SecondServer() data.DataPoint {
 	return m.DataPoints[1]
 }
 
+func (m *Measurement) ServingCell() data.DataPoint {
+	for _, dataPoint := range m.DataPoints {
+		if dataPoint.Serving {
+			return dataPoint
+		}
+	}
+	return data.ZeroDataPoint
+}
+
 func (m *Measurement) BestRSSI() int {
 	if len(m.DataPoints) == 0 {
 		return data.NoSignal
@@ -270,13 +289,13 @@ func (m *Measurement) SignalLevelDifference() int {
 	return m.DataPoints[0].RSSI - m.DataPoints[1].RSSI
 }
 
-func (m *Measurement) UsableServers() int {
+// UsableServers returns the number of servers that are usable according to the given GAN profile.
+func (m *Measurement) UsableServers(profile data.GANProfile) int {
 	result := 0
 	for _, dataPoint := range m.DataPoints {
-		if !dataPoint.IsUsable() {
-			return result
+		if profile.IsUsableRSSI(dataPoint.RSSI) {
+			result++
 		}
-		result++
 	}
 	return result
 }
@@ -0,0 +1,122 @@
+package quality
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/ftl/tetra-mess/pkg/data"
+)
+
+// FieldSummary contains all statistics of a field report in a form that can be exported.
+type FieldSummary struct {
+	Field        string       `json:"field"`
+	MinLat       float64      `json:"min_lat"`
+	MinLon       float64      `json:"min_lon"`
+	MaxLat       float64      `json:"max_lat"`
+	MaxLon       float64      `json:"max_lon"`
+	Measurements int          `json:"measurements"`
+	AverageRSSI  int          `json:"avg_rssi"`
+	AverageGAN   int          `json:"avg_gan"`
+	AverageSLD   int          `json:"avg_sld"`
+	LACs         []LACSummary `json:"lacs"`
+}
+
+type LACSummary struct {
+	LAC         uint32 `json:"lac"`
+	AverageRSSI int    `json:"avg_rssi"`
+	AverageGAN  int    `json:"avg_gan"`
+	MinRSSI     int    `json:"min_rssi"`
+	MaxRSSI     int    `json:"max_rssi"`
+}
+
+func (f *FieldReport) Summary() FieldSummary {
+	minLat, minLon, maxLat, maxLon := f.Area()
+	lacReports := f.LACReportsByRSSI()
+	lacs := make([]LACSummary, 0, len(lacReports))
+	for _, lacReport := range lacReports {
+		lacs = append(lacs, LACSummary{
+			LAC:         lacReport.LAC,
+			AverageRSSI: lacReport.AverageRSSI(),
+			AverageGAN:  lacReport.AverageGAN(),
+			MinRSSI:     lacReport.MinRSSI,
+			MaxRSSI:     lacReport.MaxRSSI,
+		})
+	}
+
+	return FieldSummary{
+		Field:        f.Field.FieldID(),
+		MinLat:       minLat,
+		MinLon:       minLon,
+		MaxLat:       maxLat,
+		MaxLon:       maxLon,
+		Measurements: len(f.Measurements),
+		AverageRSSI:  f.AverageRSSI(),
+		AverageGAN:   f.AverageGAN(),
+		AverageSLD:   f.AverageSignalLevelDifference(),
+		LACs:         lacs,
+	}
+}
+
+// Center returns the center of the field.
+func (s FieldSummary) Center() (lat float64, lon float64) {
+	return (s.MinLat + s.MaxLat) / 2, (s.MinLon + s.MaxLon) / 2
+}
+
+// WriteFieldReportsAsCSV writes one line per field. The statistics of the LACs are written into one column as
+// space separated list of <LAC>:<avg RSSI>:<min RSSI>:<max RSSI>.
+func WriteFieldReportsAsCSV(out io.Writer, _ string, _ data.Legend, fieldReports []FieldReport) error {
+	_, err := fmt.Fprintln(out, "field,center_lat,center_lon,measurements,avg_rssi,avg_gan,avg_sld,lacs")
+	if err != nil {
+		return fmt.Errorf("error writing CSV header: %w", err)
+	}
+	for _, fieldReport := range fieldReports {
+		summary := fieldReport.Summary()
+		lat, lon := summary.Center()
+		lacs := make([]string, 0, len(summary.LACs))
+		for _, lac := range summary.LACs {
+			lacs = append(lacs, fmt.Sprintf("%d:%d:%d:%d", lac.LAC, lac.AverageRSSI, lac.MinRSSI, lac.MaxRSSI))
+		}
+
+		_, err := fmt.Fprintf(out, "%s,%f,%f,%d,%d,%d,%d,%s\n",
+			summary.Field,
+			lat,
+			lon,
+			summary.Measurements,
+			summary.AverageRSSI,
+			summary.AverageGAN,
+			summary.AverageSLD,
+			strings.Join(lacs, " "))
+		if err != nil {
+			return fmt.Errorf("error writing field %s: %w", summary.Field, err)
+		}
+	}
+	return nil
+}
+
+// WriteFieldReportsAsJSON writes all fields as one JSON document.
+func WriteFieldReportsAsJSON(out io.Writer, name string, legend data.Legend, fieldReports []FieldReport) error {
+	summaries := make([]FieldSummary, 0, len(fieldReports))
+	for _, fieldReport := range fieldReports {
+		summaries = append(summaries, fieldReport.Summary())
+	}
+
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	err := encoder.Encode(struct {
+		Name       string          `json:"name,omitempty"`
+		GANProfile data.GANProfile `json:"gan_profile"`
+		Legend     data.Legend     `json:"legend"`
+		Fields     []FieldSummary  `json:"fields"`
+	}{
+		Name:       name,
+		GANProfile: legend.Profile,
+		Legend:     legend,
+		Fields:     summaries,
+	})
+	if err != nil {
+		return fmt.Errorf("error writing JSON to output: %w", err)
+	}
+	return nil
+}
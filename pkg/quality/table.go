@@ -0,0 +1,122 @@
+package quality
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ftl/tetra-mess/pkg/data"
+)
+
+// TableFormat defines the delimiter and the decimal separator of a measurement table, e.g. ; and , for spreadsheets
+// with German locale.
+type TableFormat struct {
+	Delimiter        rune
+	DecimalSeparator rune
+}
+
+var DefaultTableFormat = TableFormat{Delimiter: ',', DecimalSeparator: '.'}
+
+func (f TableFormat) Validate() error {
+	if f.Delimiter == f.DecimalSeparator {
+		return fmt.Errorf("the delimiter and the decimal separator must be different")
+	}
+	if f.Delimiter == '"' || f.Delimiter == '\r' || f.Delimiter == '\n' {
+		return fmt.Errorf("invalid delimiter %q", f.Delimiter)
+	}
+	if f.DecimalSeparator != '.' && f.DecimalSeparator != ',' {
+		return fmt.Errorf("invalid decimal separator %q, use . or ,", f.DecimalSeparator)
+	}
+	return nil
+}
+
+// tableTimestampFormat is understood by common spreadsheet applications.
+const tableTimestampFormat = "2006-01-02 15:04:05"
+
+// tableServers is the number of servers per measurement in the table.
+const tableServers = 3
+
+var tableServerNames = [tableServers]string{"best", "second", "third"}
+
+// TableWriter writes one row per measurement: the time in local time, the position, the three best servers, the SLD,
+// and the number of usable servers. The carrier is written in decimal, so that spreadsheets do not misread hexadecimal
+// values like 3e8 as numbers. Without signal, the RSSI, Cx and SLD cells are left empty, so that they are not included
+// in averages.
+type TableWriter struct {
+	out     *csv.Writer
+	format  TableFormat
+	profile data.GANProfile
+}
+
+func NewTableWriter(out io.Writer, format TableFormat, profile data.GANProfile) *TableWriter {
+	writer := csv.NewWriter(out)
+	writer.Comma = format.Delimiter
+	return &TableWriter{
+		out:     writer,
+		format:  format,
+		profile: profile,
+	}
+}
+
+func (w *TableWriter) WriteHeader() error {
+	header := []string{"time", "device", "lat", "lon", "sats"}
+	for _, server := range tableServerNames {
+		header = append(header, server+"_lac", server+"_carrier", server+"_rssi", server+"_cx")
+	}
+	header = append(header, "sld", "usable_servers")
+	return w.out.Write(header)
+}
+
+func (w *TableWriter) Write(measurement Measurement) error {
+	if len(measurement.DataPoints) == 0 {
+		return nil
+	}
+	bestServer := measurement.BestServer()
+	row := []string{
+		bestServer.Timestamp.In(time.Local).Format(tableTimestampFormat),
+		bestServer.Device,
+		w.formatFloat(bestServer.Latitude, 6),
+		w.formatFloat(bestServer.Longitude, 6),
+		strconv.Itoa(bestServer.Satellites),
+	}
+	for i := range tableServers {
+		if i >= len(measurement.DataPoints) {
+			row = append(row, "", "", "", "")
+			continue
+		}
+		server := measurement.DataPoints[i]
+		row = append(row,
+			strconv.FormatUint(uint64(server.LAC), 10),
+			strconv.FormatUint(uint64(server.Carrier), 10),
+		)
+		if server.RSSI == data.NoSignal {
+			row = append(row, "", "")
+		} else {
+			row = append(row, strconv.Itoa(server.RSSI), strconv.Itoa(server.Cx))
+		}
+	}
+	sld := ""
+	if len(measurement.DataPoints) > 1 && measurement.DataPoints[0].RSSI != data.NoSignal && measurement.DataPoints[1].RSSI != data.NoSignal {
+		sld = strconv.Itoa(measurement.SignalLevelDifference())
+	}
+	row = append(row, sld, strconv.Itoa(measurement.UsableServers(w.profile)))
+
+	return w.out.Write(row)
+}
+
+// Flush writes all buffered rows and returns the first error that occurred while writing.
+func (w *TableWriter) Flush() error {
+	w.out.Flush()
+	return w.out.Error()
+}
+
+func (w *TableWriter) formatFloat(value float64, precision int) string {
+	result := strconv.FormatFloat(value, 'f', precision, 64)
+	if w.format.DecimalSeparator != '.' {
+		result = strings.ReplaceAll(result, ".", string(w.format.DecimalSeparator))
+	}
+	return result
+}
@@ -0,0 +1,64 @@
+package quality
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ftl/tetra-mess/pkg/data"
+)
+
+func TestTableWriter(t *testing.T) {
+	timestamp := time.Date(2026, 1, 2, 10, 0, 0, 0, time.Local)
+	at := func(lac uint32, carrier uint32, rssi int, cx int) data.DataPoint {
+		return data.DataPoint{Latitude: 52.5, Longitude: 13.25, Satellites: 7, Timestamp: timestamp, LAC: lac, Carrier: carrier, RSSI: rssi, Cx: cx}
+	}
+	tt := []struct {
+		name       string
+		format     TableFormat
+		dataPoints []data.DataPoint
+		expected   string
+	}{
+		{
+			name:       "carrier in decimal",
+			format:     DefaultTableFormat,
+			dataPoints: []data.DataPoint{at(100, 0x3e8, -70, 30), at(101, 0x1234, -80, 20)},
+			expected:   "2026-01-02 10:00:00,,52.500000,13.250000,7,100,1000,-70,30,101,4660,-80,20,,,,,10,2\n",
+		},
+		{
+			name:       "no signal",
+			format:     DefaultTableFormat,
+			dataPoints: []data.DataPoint{at(100, 0x3e8, -70, 30), at(101, 0x3e9, data.NoSignal, 0)},
+			expected:   "2026-01-02 10:00:00,,52.500000,13.250000,7,100,1000,-70,30,101,1001,,,,,,,,1\n",
+		},
+		{
+			name:       "German locale",
+			format:     TableFormat{Delimiter: ';', DecimalSeparator: ','},
+			dataPoints: []data.DataPoint{at(100, 0x3e8, -70, 30)},
+			expected:   "2026-01-02 10:00:00;;52,500000;13,250000;7;100;1000;-70;30;;;;;;;;;;1\n",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			measurement := Measurement{}
+			measurement.Add(tc.dataPoints...)
+			out := &bytes.Buffer{}
+			writer := NewTableWriter(out, tc.format, data.DefaultGANProfile)
+
+			err := writer.Write(measurement)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = writer.Flush()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			actual := out.String()
+			if actual != tc.expected {
+				t.Errorf("expected\n%s\ngot\n%s", strings.TrimSpace(tc.expected), strings.TrimSpace(actual))
+			}
+		})
+	}
+}
@@ -0,0 +1,174 @@
+package scanner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/ftl/tetra-cli/pkg/radio"
+	"github.com/ftl/tetra-pei/ctrl"
+
+	"github.com/ftl/tetra-mess/pkg/connection"
+	"github.com/ftl/tetra-mess/pkg/data"
+)
+
+type RadioType string
+
+const (
+	AutoDetectRadio RadioType = "auto"
+	MotorolaRadio   RadioType = "motorola"
+	SepuraRadio     RadioType = "sepura"
+	HyteraRadio     RadioType = "hytera"
+	GenericRadio    RadioType = "generic"
+)
+
+var RadioTypes = []RadioType{AutoDetectRadio, MotorolaRadio, SepuraRadio, HyteraRadio, GenericRadio}
+
+// CellListProvider requests the list of currently received cells from the radio.
+type CellListProvider interface {
+	RequestCellList(ctx context.Context, pei radio.PEI) ([]data.CellInfo, error)
+}
+
+type CellListProviderFunc func(ctx context.Context, pei radio.PEI) ([]data.CellInfo, error)
+
+func (f CellListProviderFunc) RequestCellList(ctx context.Context, pei radio.PEI) ([]data.CellInfo, error) {
+	return f(ctx, pei)
+}
+
+// MotorolaCellList uses the proprietary AT+GCLI? command, which reports the serving cell and all neighbour cells.
+var MotorolaCellList CellListProvider = CellListProviderFunc(RequestCellListInformation)
+
+// GenericCellList uses only standard PEI commands. It reports the serving cell with its LAC and RSSI,
+// the carrier and Cx are not available.
+var GenericCellList CellListProvider = CellListProviderFunc(RequestServingCellInformation)
+
+// SepuraCellList is used for Sepura radios. Their neighbour cell list is not available through the documented PEI
+// commands, so only the serving cell is reported, like with GenericCellList.
+var SepuraCellList CellListProvider = GenericCellList
+
+// HyteraCellList is used for Hytera radios. Their neighbour cell list is not available through the documented PEI
+// commands, so only the serving cell is reported, like with GenericCellList.
+var HyteraCellList CellListProvider = GenericCellList
+
+// NewCellListProvider returns the CellListProvider for the given type of radio.
+func NewCellListProvider(radioType RadioType) (CellListProvider, error) {
+	switch RadioType(strings.ToLower(string(radioType))) {
+	case AutoDetectRadio, "":
+		return &autoDetectCellList{}, nil
+	case MotorolaRadio:
+		return MotorolaCellList, nil
+	case SepuraRadio:
+		return SepuraCellList, nil
+	case HyteraRadio:
+		return HyteraCellList, nil
+	case GenericRadio:
+		return GenericCellList, nil
+	default:
+		return nil, fmt.Errorf("unknown radio type: %s", radioType)
+	}
+}
+
+// DetectRadioType uses AT+GMI to find out the manufacturer of the radio.
+func DetectRadioType(ctx context.Context, pei radio.PEI) (RadioType, error) {
+	response, err := pei.AT(ctx, "AT+GMI")
+	if err != nil {
+		return "", err
+	}
+	manufacturer := strings.ToLower(strings.Join(response, " "))
+	switch {
+	case strings.Contains(manufacturer, "motorola"):
+		return MotorolaRadio, nil
+	case strings.Contains(manufacturer, "sepura"):
+		return SepuraRadio, nil
+	case strings.Contains(manufacturer, "hytera"):
+		return HyteraRadio, nil
+	default:
+		return GenericRadio, nil
+	}
+}
+
+// autoDetectCellList detects the type of radio on the first request and uses the matching provider from then on.
+// As long as the radio does not answer AT+GMI, it is handled as generic radio and the detection is repeated with the
+// next request.
+type autoDetectCellList struct {
+	mutex    sync.Mutex
+	provider CellListProvider
+}
+
+func (l *autoDetectCellList) RequestCellList(ctx context.Context, pei radio.PEI) ([]data.CellInfo, error) {
+	l.mutex.Lock()
+	if l.provider == nil {
+		radioType, err := DetectRadioType(ctx, pei)
+		switch {
+		case errors.Is(err, connection.ErrDisconnected), err != nil && ctx.Err() != nil:
+			l.mutex.Unlock()
+			return nil, fmt.Errorf("cannot detect radio type: %w", err)
+		case err != nil:
+			l.mutex.Unlock()
+			return GenericCellList.RequestCellList(ctx, pei)
+		}
+		l.provider, err = NewCellListProvider(radioType)
+		if err != nil {
+			l.mutex.Unlock()
+			return nil, err
+		}
+	}
+	provider := l.provider
+	l.mutex.Unlock()
+
+	return provider.RequestCellList(ctx, pei)
+}
+
+var registrationResponse = regexp.MustCompile(`^\+CREG: (\d+),(\d+)`)
+
+// RequestServingCellInformation reads the LAC of the serving cell using AT+CREG? and its RSSI using AT+CSQ? (see
+// ctrl.RequestSignalStrength).
+func RequestServingCellInformation(ctx context.Context, pei radio.PEI) ([]data.CellInfo, error) {
+	lac, err := RequestServingLAC(ctx, pei)
+	if err != nil {
+		return nil, err
+	}
+
+	rssi, err := ctrl.RequestSignalStrength(ctx, pei)
+	if err != nil {
+		rssi = data.NoSignal
+	}
+
+	return []data.CellInfo{{
+		LAC:     lac,
+		RSSI:    rssi,
+		Serving: true,
+	}}, nil
+}
+
+// RequestServingLAC reads the LAC of the cell the radio is currently registered to using AT+CREG?
+func RequestServingLAC(ctx context.Context, pei radio.PEI) (uint32, error) {
+	response, err := pei.AT(ctx, "AT+CREG?")
+	if err != nil {
+		return 0, err
+	}
+	if len(response) == 0 {
+		return 0, fmt.Errorf("empty response received")
+	}
+
+	parts := registrationResponse.FindStringSubmatch(strings.ToUpper(strings.TrimSpace(response[0])))
+	if len(parts) != 3 {
+		return 0, fmt.Errorf("invalid registration response: %s", response[0])
+	}
+	switch parts[1] {
+	case "1", "5":
+		// registered
+	default:
+		return 0, fmt.Errorf("radio is not registered (status %s)", parts[1])
+	}
+
+	lac, err := strconv.ParseUint(parts[2], 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid LAC: %w", err)
+	}
+	return uint32(lac), nil
+}
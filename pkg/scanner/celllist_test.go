@@ -0,0 +1,86 @@
+package scanner
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/ftl/tetra-cli/pkg/radio"
+)
+
+// scriptedPEI answers each request with the next of its scripted responses and records all requests. A nil response
+// or a request without further responses fails.
+type scriptedPEI struct {
+	radio.PEI
+
+	responses map[string][][]string
+	requests  []string
+}
+
+func (p *scriptedPEI) AT(_ context.Context, request string) ([]string, error) {
+	p.requests = append(p.requests, request)
+	responses := p.responses[request]
+	if len(responses) == 0 {
+		return nil, errors.New("ERROR")
+	}
+	p.responses[request] = responses[1:]
+	if responses[0] == nil {
+		return nil, errors.New("ERROR")
+	}
+	return responses[0], nil
+}
+
+func (p *scriptedPEI) Request(ctx context.Context, request string) ([]string, error) {
+	return p.AT(ctx, request)
+}
+
+func TestDetectRadioType(t *testing.T) {
+	tt := []struct {
+		manufacturer string
+		expected     RadioType
+	}{
+		{manufacturer: "Motorola Solutions", expected: MotorolaRadio},
+		{manufacturer: "SEPURA", expected: SepuraRadio},
+		{manufacturer: "Hytera Communications", expected: HyteraRadio},
+		{manufacturer: "Airbus", expected: GenericRadio},
+	}
+	for _, tc := range tt {
+		t.Run(tc.manufacturer, func(t *testing.T) {
+			pei := &scriptedPEI{responses: map[string][][]string{"AT+GMI": {{tc.manufacturer}}}}
+			actual, err := DetectRadioType(context.Background(), pei)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if actual != tc.expected {
+				t.Errorf("expected %s, got %s", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestAutoDetectRetriesAfterFailedDetection(t *testing.T) {
+	pei := &scriptedPEI{responses: map[string][][]string{
+		"AT+GMI":   {nil, {"Motorola Solutions"}},
+		"AT+CREG?": {{"+CREG: 1,12345"}},
+		"AT+GCLI?": {{"+GCLI: 0"}},
+	}}
+	provider, err := NewCellListProvider(AutoDetectRadio)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = provider.RequestCellList(context.Background(), pei)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Contains(pei.requests, "AT+CREG?") {
+		t.Errorf("the generic provider should be used while the detection fails, got %v", pei.requests)
+	}
+
+	pei.requests = nil
+	provider.RequestCellList(context.Background(), pei)
+	if len(pei.requests) < 2 || pei.requests[0] != "AT+GMI" || pei.requests[1] != "AT+GCLI?" {
+		t.Errorf("the detection should be repeated and the Motorola provider used, got %v", pei.requests)
+	}
+}
@@ -37,12 +37,14 @@ func RequestCellListInformation(ctx context.Context, pei radio.PEI) ([]data.Cell
 	}
 
 	result := make([]data.CellInfo, 0, count)
-	for _, line := range response[1:] {
+	for i, line := range response[1:] {
 		cellInfo, err := parseCellInfo(line)
 		if err != nil {
 			log.Printf("invalid cell info line: %v", err) // TODO: print to stderr
 			continue
 		}
+		// the radio lists the serving cell first
+		cellInfo.Serving = i == 0
 		result = append(result, cellInfo)
 	}
 
@@ -0,0 +1,172 @@
+package scanner
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/ftl/tetra-cli/pkg/radio"
+
+	"github.com/ftl/tetra-mess/pkg/connection"
+)
+
+var (
+	registrationIndication = regexp.MustCompile(`^\+CREG: (\d+),(\d+)`)
+	gpsPositionIndication  = regexp.MustCompile(`^\+GPSPOS: .*,(\d+)$`)
+)
+
+const (
+	registrationPrefix = "+CREG:"
+	gpsPositionPrefix  = "+GPSPOS:"
+)
+
+// requestsByIndication maps the requests whose responses are also handled as indications to the prefix of the indication.
+var requestsByIndication = map[string]string{
+	"AT+CREG?":   registrationPrefix,
+	"AT+GPSPOS?": gpsPositionPrefix,
+}
+
+// eventTrigger watches the unsolicited indications of the radio and signals when an immediate scan is required:
+// when the radio registers to a different cell (cell reselection or handover), and when the GPS receiver acquires a fix.
+type eventTrigger struct {
+	triggers chan string
+
+	mutex        sync.Mutex
+	lastLines    map[string]string
+	lastLAC      string
+	lastFix      bool
+	lastFixKnown bool
+}
+
+func newEventTrigger() *eventTrigger {
+	return &eventTrigger{
+		triggers:  make(chan string, 1),
+		lastLines: make(map[string]string),
+	}
+}
+
+// Subscribe registers the indication handlers and enables the unsolicited registration indications. If the PEI
+// reconnects automatically, the indications are enabled again after every reconnect, since the initialization of
+// the radio (ATZ) disables them.
+// It returns a PEI that must be used for all further requests, also in case of an error, because the
+// responses to AT+CREG? and AT+GPSPOS? are consumed by the indication handlers.
+func (t *eventTrigger) Subscribe(ctx context.Context, pei radio.PEI) (radio.PEI, error) {
+	result := &indicationPEI{PEI: pei, trigger: t}
+
+	err := pei.AddIndication(registrationPrefix, 0, t.handleRegistration)
+	if err != nil {
+		return result, err
+	}
+	err = pei.AddIndication(gpsPositionPrefix, 0, t.handleGPSPosition)
+	if err != nil {
+		return result, err
+	}
+
+	if supervised, ok := connection.AsSupervised(pei); ok {
+		supervised.AddInitializer(enableRegistrationIndications)
+	}
+
+	return result, enableRegistrationIndications(ctx, pei)
+}
+
+func enableRegistrationIndications(ctx context.Context, pei radio.PEI) error {
+	_, err := pei.AT(ctx, "AT+CREG=1")
+	return err
+}
+
+// the handlers are called from within the PEI's read loop, they MUST NOT block
+
+func (t *eventTrigger) handleRegistration(lines []string) {
+	line := strings.ToUpper(strings.TrimSpace(lines[0]))
+	parts := registrationIndication.FindStringSubmatch(line)
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.lastLines[registrationPrefix] = line
+	if len(parts) != 3 {
+		return
+	}
+	lac := parts[2]
+	if t.lastLAC == lac {
+		return
+	}
+
+	changed := t.lastLAC != ""
+	t.lastLAC = lac
+	if changed {
+		t.trigger("cell changed to LAC " + lac)
+	}
+}
+
+func (t *eventTrigger) handleGPSPosition(lines []string) {
+	line := strings.ToUpper(strings.TrimSpace(lines[0]))
+	parts := gpsPositionIndication.FindStringSubmatch(line)
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.lastLines[gpsPositionPrefix] = line
+	if len(parts) != 2 {
+		return
+	}
+	fix := parts[1] != "0"
+	if t.lastFixKnown && t.lastFix == fix {
+		return
+	}
+
+	acquired := fix && t.lastFixKnown
+	t.lastFix = fix
+	t.lastFixKnown = true
+	if acquired {
+		t.trigger("GPS fix acquired")
+	}
+}
+
+func (t *eventTrigger) trigger(reason string) {
+	select {
+	case t.triggers <- reason:
+	default:
+		// a scan is already pending
+	}
+}
+
+func (t *eventTrigger) takeLastLine(prefix string) string {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	result := t.lastLines[prefix]
+	delete(t.lastLines, prefix)
+	return result
+}
+
+// indicationPEI restores the responses that were consumed by the indication handlers.
+type indicationPEI struct {
+	radio.PEI
+	trigger *eventTrigger
+}
+
+func (p *indicationPEI) Request(ctx context.Context, request string) ([]string, error) {
+	return p.AT(ctx, request)
+}
+
+func (p *indicationPEI) AT(ctx context.Context, request string) ([]string, error) {
+	prefix, ok := requestsByIndication[strings.ToUpper(request)]
+	if ok {
+		// drop any unsolicited indication received before the request
+		p.trigger.takeLastLine(prefix)
+	}
+
+	response, err := p.PEI.AT(ctx, request)
+	if err != nil || !ok || len(response) > 0 {
+		return response, err
+	}
+
+	// single line indications are handled before the final OK of the response is received
+	line := p.trigger.takeLastLine(prefix)
+	if line == "" {
+		return response, nil
+	}
+	return []string{line}, nil
+}
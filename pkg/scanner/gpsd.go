@@ -0,0 +1,136 @@
+package scanner
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	"github.com/ftl/tetra-mess/pkg/data"
+)
+
+const (
+	DefaultGPSDAddress   = "localhost:2947"
+	gpsdReconnectTimeout = 5 * time.Second
+	gpsdWatchCommand     = `?WATCH={"enable":true,"json":true}` + "\n"
+)
+
+// NewGPSDPosition connects to the gpsd instance at the given address and keeps the latest fix. If the connection
+// fails, it is retried until the context is done.
+func NewGPSDPosition(ctx context.Context, address string, log Logger) PositionSource {
+	result := &latestFix{}
+	go func() {
+		for {
+			err := watchGPSD(ctx, address, result.Update)
+			if ctx.Err() != nil {
+				return
+			}
+			log("cannot read from gpsd at %s, retrying: %v", address, err)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(gpsdReconnectTimeout):
+			}
+		}
+	}()
+	return result
+}
+
+func watchGPSD(ctx context.Context, address string, report func(data.Position)) error {
+	dialer := net.Dialer{}
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	_, err = io.WriteString(conn, gpsdWatchCommand)
+	if err != nil {
+		return err
+	}
+
+	return ReadGPSD(conn, report)
+}
+
+type gpsdReport struct {
+	Class string  `json:"class"`
+	Mode  int     `json:"mode"`
+	Time  string  `json:"time"`
+	Lat   float64 `json:"lat"`
+	Lon   float64 `json:"lon"`
+	Alt   float64 `json:"alt"`
+	Speed float64 `json:"speed"`
+	Track float64 `json:"track"`
+	HDOP  float64 `json:"hdop"`
+
+	USat       *int `json:"uSat"`
+	Satellites []struct {
+		Used bool `json:"used"`
+	} `json:"satellites"`
+}
+
+// ReadGPSD reads the JSON reports of gpsd from the given reader and reports every position from a TPV report.
+// TPV reports without time are skipped. The number of satellites and the HDOP are taken from the SKY reports.
+func ReadGPSD(in io.Reader, report func(data.Position)) error {
+	satellites := 0
+	hdop := 0.0
+	lineScanner := bufio.NewScanner(in)
+	for lineScanner.Scan() {
+		var r gpsdReport
+		err := json.Unmarshal(lineScanner.Bytes(), &r)
+		if err != nil {
+			return fmt.Errorf("invalid gpsd report: %w", err)
+		}
+
+		switch r.Class {
+		case "SKY":
+			satellites = r.usedSatellites()
+			hdop = r.HDOP
+		case "TPV":
+			// gpsd omits the time or sends it empty if the receiver does not know it yet
+			if r.Time == "" {
+				continue
+			}
+			timestamp, err := time.Parse(time.RFC3339Nano, r.Time)
+			if err != nil {
+				continue
+			}
+			// mode 2: 2D fix, mode 3: 3D fix
+			if r.Mode < 2 {
+				report(data.Position{Timestamp: timestamp, FixType: data.NoFix})
+				continue
+			}
+			report(data.Position{
+				Latitude:   r.Lat,
+				Longitude:  r.Lon,
+				Satellites: max(satellites, 1),
+				Timestamp:  timestamp,
+				Altitude:   r.Alt,
+				Speed:      r.Speed,
+				Heading:    r.Track,
+				FixType:    data.FixType(r.Mode),
+				HDOP:       hdop,
+			})
+		}
+	}
+	return lineScanner.Err()
+}
+
+func (r gpsdReport) usedSatellites() int {
+	if r.USat != nil {
+		return *r.USat
+	}
+	result := 0
+	for _, satellite := range r.Satellites {
+		if satellite.Used {
+			result++
+		}
+	}
+	return result
+}
@@ -0,0 +1,64 @@
+package scanner
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ftl/tetra-mess/pkg/data"
+)
+
+func TestReadGPSD(t *testing.T) {
+	tt := []struct {
+		name     string
+		lines    []string
+		expected []data.Position
+	}{
+		{
+			name: "position with satellites and HDOP from SKY",
+			lines: []string{
+				`{"class":"VERSION","release":"3.25","rev":"3.25","proto_major":3,"proto_minor":15}`,
+				`{"class":"SKY","device":"/dev/ttyACM0","hdop":0.92,"uSat":9,"satellites":[{"PRN":4,"used":true}]}`,
+				`{"class":"TPV","device":"/dev/ttyACM0","mode":3,"time":"2026-10-17T08:15:02.000Z","lat":52.349885,"lon":13.377718,"alt":35.2,"track":271.5,"speed":13.9}`,
+			},
+			expected: []data.Position{
+				{Latitude: 52.349885, Longitude: 13.377718, Satellites: 9, Timestamp: time.Date(2026, 10, 17, 8, 15, 2, 0, time.UTC), Altitude: 35.2, Speed: 13.9, Heading: 271.5, FixType: data.Fix3D, HDOP: 0.92},
+			},
+		},
+		{
+			name: "satellites are counted without uSat",
+			lines: []string{
+				`{"class":"SKY","hdop":1.5,"satellites":[{"PRN":4,"used":true},{"PRN":7,"used":false},{"PRN":9,"used":true}]}`,
+				`{"class":"TPV","mode":2,"time":"2026-10-17T08:15:03.500Z","lat":52.35,"lon":13.38}`,
+			},
+			expected: []data.Position{
+				{Latitude: 52.35, Longitude: 13.38, Satellites: 2, Timestamp: time.Date(2026, 10, 17, 8, 15, 3, 500000000, time.UTC), FixType: data.Fix2D, HDOP: 1.5},
+			},
+		},
+		{
+			name: "reports without time are skipped",
+			lines: []string{
+				`{"class":"TPV","device":"/dev/ttyACM0","mode":1}`,
+				`{"class":"TPV","device":"/dev/ttyACM0","mode":1,"time":""}`,
+				`{"class":"TPV","device":"/dev/ttyACM0","mode":1,"time":"2026-10-17T08:15:04.000Z"}`,
+				`{"class":"TPV","device":"/dev/ttyACM0","mode":3,"time":"2026-10-17T08:15:05.000Z","lat":52.35,"lon":13.38}`,
+			},
+			expected: []data.Position{
+				{Timestamp: time.Date(2026, 10, 17, 8, 15, 4, 0, time.UTC), FixType: data.NoFix},
+				{Latitude: 52.35, Longitude: 13.38, Satellites: 1, Timestamp: time.Date(2026, 10, 17, 8, 15, 5, 0, time.UTC), FixType: data.Fix3D},
+			},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var actual []data.Position
+			err := ReadGPSD(strings.NewReader(strings.Join(tc.lines, "\n")), func(position data.Position) {
+				actual = append(actual, position)
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertPositions(t, tc.expected, actual)
+		})
+	}
+}
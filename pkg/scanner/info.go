@@ -0,0 +1,60 @@
+package scanner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ftl/tetra-cli/pkg/radio"
+
+	"github.com/ftl/tetra-mess/pkg/data"
+)
+
+// RequestRadioInfo reads the manufacturer (AT+GMI), the model (AT+GMM), the firmware version (AT+GMR), and the ISSI
+// (AT+CNUMF?) of the radio. The result contains all information that is available, even if some requests fail.
+func RequestRadioInfo(ctx context.Context, pei radio.PEI) (data.RadioInfo, error) {
+	var result data.RadioInfo
+	var errs []error
+	request := func(request string) string {
+		response, err := pei.AT(ctx, request)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", request, err))
+			return ""
+		}
+		return strings.TrimSpace(strings.Join(response, " "))
+	}
+
+	result.Manufacturer = request("AT+GMI")
+	result.Model = request("AT+GMM")
+	result.Firmware = request("AT+GMR")
+	issi, err := ParseISSI(request("AT+CNUMF?"))
+	if err != nil {
+		errs = append(errs, err)
+	}
+	result.ISSI = issi
+
+	return result, errors.Join(errs...)
+}
+
+// ParseISSI extracts the ISSI from the response to AT+CNUMF?, e.g. +CNUMF: 6,262100001234567. The ITSI consists of
+// the MCC (3 digits), the MNC (4 digits), and the ISSI (8 digits).
+func ParseISSI(response string) (string, error) {
+	if response == "" {
+		return "", nil
+	}
+	_, values, found := strings.Cut(response, "+CNUMF:")
+	if !found {
+		return "", fmt.Errorf("invalid response to AT+CNUMF?: %s", response)
+	}
+	fields := strings.Split(values, ",")
+	itsi := strings.TrimSpace(fields[len(fields)-1])
+	if len(itsi) < 8 {
+		return "", fmt.Errorf("invalid ITSI: %s", itsi)
+	}
+	issi := strings.TrimLeft(itsi[len(itsi)-8:], "0")
+	if issi == "" {
+		issi = "0"
+	}
+	return issi, nil
+}
@@ -6,53 +6,121 @@ import (
 
 	"github.com/ftl/tetra-cli/pkg/radio"
 
+	"github.com/ftl/tetra-mess/pkg/data"
 	"github.com/ftl/tetra-mess/pkg/quality"
 )
 
+// ScanLoopFactory creates a new ScanLoop for the device with the given name.
+type ScanLoopFactory func(device string, out chan<- DataPoint, logger Logger) *ScanLoop
+
 type ScanLoop struct {
-	out          chan<- DataPoint
-	logger       Logger
-	scanInterval time.Duration
-	scanTimeout  time.Duration
+	out         chan<- DataPoint
+	logger      Logger
+	device      string
+	positions   PositionSource
+	cellList    CellListProvider
+	schedule    ScanSchedule
+	scanTimeout time.Duration
+	events      *eventTrigger
 }
 
-func NewScanLoop(scanInterval, scanTimeout time.Duration, out chan<- DataPoint, logger Logger) *ScanLoop {
+func NewScanLoop(schedule ScanSchedule, scanTimeout time.Duration, positions PositionSource, cellList CellListProvider, out chan<- DataPoint, logger Logger) *ScanLoop {
 	return &ScanLoop{
-		out:          out,
-		logger:       logger,
-		scanInterval: scanInterval,
-		scanTimeout:  scanTimeout,
+		out:         out,
+		logger:      logger,
+		positions:   positions,
+		cellList:    cellList,
+		schedule:    schedule,
+		scanTimeout: scanTimeout,
 	}
 }
 
+// SetDevice sets the name of the device that is scanned by this loop. All data points are tagged with this name.
+func (l *ScanLoop) SetDevice(name string) {
+	l.device = name
+}
+
+// EnableEventTriggers lets the loop scan immediately on cell changes and when the GPS receiver acquires a fix,
+// additionally to the periodic scans.
+func (l *ScanLoop) EnableEventTriggers() {
+	l.events = newEventTrigger()
+}
+
 func (l *ScanLoop) Run(ctx context.Context, pei radio.PEI) {
-	scanTicker := time.NewTicker(l.scanInterval)
-	defer scanTicker.Stop()
+	pollTicker := time.NewTicker(l.schedule.PollInterval())
+	defer pollTicker.Stop()
+
+	var triggers <-chan string
+	if l.events != nil {
+		var err error
+		pei, err = l.events.Subscribe(ctx, pei)
+		if err != nil {
+			l.log("cannot subscribe to radio events, using only periodic scans: %v", err)
+		} else {
+			triggers = l.events.triggers
+		}
+	}
 
 	for {
 		select {
 		case <-ctx.Done():
 			return
-		case <-scanTicker.C:
-			select {
-			case l.out <- l.scan(ctx, pei):
-			default:
-				l.log("cannot report scan data point, output channel not ready")
+		case <-pollTicker.C:
+			positions, due := l.scanDue(ctx, pei)
+			if !due {
+				continue
 			}
+			l.report(l.scan(ctx, pei, positions))
+		case reason := <-triggers:
+			l.log("scan triggered: %s", reason)
+			l.report(l.scan(ctx, pei, l.positions))
+			pollTicker.Reset(l.schedule.PollInterval())
 		}
 	}
 }
 
-func (l *ScanLoop) scan(ctx context.Context, pei radio.PEI) DataPoint {
+func (l *ScanLoop) report(dataPoint DataPoint) {
+	select {
+	case l.out <- dataPoint:
+	default:
+		l.log("cannot report scan data point, output channel not ready")
+	}
+}
+
+// scanDue asks the schedule if a scan is due. It returns the position source for the scan: if the schedule needs the
+// current position, the scan reuses the position that was just polled instead of requesting it again.
+func (l *ScanLoop) scanDue(ctx context.Context, pei radio.PEI) (PositionSource, bool) {
+	if !l.schedule.PositionRequired() {
+		return l.positions, l.schedule.ScanDue(data.NoPosition)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, l.scanTimeout)
+	defer cancel()
+
+	position, err := l.positions.RequestPosition(ctx, pei)
+	polled := PositionSourceFunc(func(context.Context, radio.PEI) (data.Position, error) {
+		return position, err
+	})
+
+	// errors are reported by the scan itself
+	return polled, l.schedule.ScanDue(RequestPosition(ctx, pei, polled, func(string, ...any) {}))
+}
+
+func (l *ScanLoop) scan(ctx context.Context, pei radio.PEI, positions PositionSource) DataPoint {
 	ctx, cancel := context.WithTimeout(ctx, l.scanTimeout)
 	defer cancel()
 
-	position, dataPoints := ScanSignalAndPosition(ctx, pei, l.log)
+	position, dataPoints := ScanSignalAndPosition(ctx, pei, positions, l.cellList, l.log)
+	l.schedule.Scanned(position)
+	for i := range dataPoints {
+		dataPoints[i].Device = l.device
+	}
 
 	measurement := quality.Measurement{}
 	measurement.Add(dataPoints...)
 
 	return DataPoint{
+		Device:      l.device,
 		Position:    position,
 		Measurement: measurement,
 	}
@@ -0,0 +1,292 @@
+package scanner
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jacobsa/go-serial/serial"
+
+	"github.com/ftl/tetra-mess/pkg/data"
+)
+
+const defaultNMEABaudRate = 4800
+
+// OpenNMEAPosition reads a NMEA 0183 stream from the given serial device or file. The baud rate of a serial device
+// can be appended to the device name, e.g. /dev/ttyUSB0@9600. Without baud rate, character devices are opened with
+// 4800 baud. A regular file, e.g. a recorded NMEA log, is replayed in the pace of its sentence times.
+func OpenNMEAPosition(ctx context.Context, address string, log Logger) (PositionSource, error) {
+	in, err := openNMEAStream(address)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open NMEA stream %s: %w", address, err)
+	}
+
+	result := &latestFix{}
+	report := result.Update
+	if isRegularFile(in) {
+		report = pacedBySentenceTime(ctx, report)
+	}
+	go func() {
+		<-ctx.Done()
+		in.Close()
+	}()
+	go func() {
+		err := ReadNMEA(in, report)
+		if err != nil && ctx.Err() == nil {
+			log("cannot read NMEA stream %s: %v", address, err)
+		}
+	}()
+
+	return result, nil
+}
+
+func isRegularFile(in io.Reader) bool {
+	file, ok := in.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := file.Stat()
+	return err == nil && info.Mode().IsRegular()
+}
+
+// pacedBySentenceTime delays each position until the time since the first position has passed, so that the positions
+// of a recorded NMEA log are reported like the positions of a live receiver.
+func pacedBySentenceTime(ctx context.Context, report func(data.Position)) func(data.Position) {
+	var first, started time.Time
+	return func(position data.Position) {
+		if started.IsZero() {
+			first = position.Timestamp
+			started = time.Now()
+		} else if delay := position.Timestamp.Sub(first) - time.Since(started); delay > 0 {
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+				return
+			}
+		}
+		report(position)
+	}
+}
+
+func openNMEAStream(address string) (io.ReadCloser, error) {
+	filename, rawBaudRate, hasBaudRate := strings.Cut(address, "@")
+	baudRate := defaultNMEABaudRate
+	if hasBaudRate {
+		var err error
+		baudRate, err = strconv.Atoi(rawBaudRate)
+		if err != nil {
+			return nil, fmt.Errorf("invalid baud rate: %w", err)
+		}
+	} else {
+		info, err := os.Stat(filename)
+		if err != nil {
+			return nil, err
+		}
+		if info.Mode()&os.ModeCharDevice == 0 {
+			return os.Open(filename)
+		}
+	}
+
+	return serial.Open(serial.OpenOptions{
+		PortName:        filename,
+		BaudRate:        uint(baudRate),
+		DataBits:        8,
+		StopBits:        1,
+		ParityMode:      serial.PARITY_NONE,
+		MinimumReadSize: 1,
+	})
+}
+
+// ReadNMEA reads NMEA 0183 sentences from the given reader and reports every position from a GGA sentence.
+// The date, speed and heading are taken from the RMC sentences, the fix type from the GSA sentences. ReadNMEA returns when the reader is exhausted.
+func ReadNMEA(in io.Reader, report func(data.Position)) error {
+	parser := nmeaParser{}
+	lineScanner := bufio.NewScanner(in)
+	for lineScanner.Scan() {
+		position, ok := parser.Parse(lineScanner.Text())
+		if ok {
+			report(position)
+		}
+	}
+	return lineScanner.Err()
+}
+
+const knotsToMetresPerSecond = 1852.0 / 3600.0
+
+type nmeaParser struct {
+	date          time.Time
+	lastTimestamp time.Time
+	speed         float64
+	heading       float64
+	fixType       data.FixType
+}
+
+// Parse parses a single NMEA sentence. It returns true, if the sentence contained a position.
+func (p *nmeaParser) Parse(line string) (data.Position, bool) {
+	fields, err := splitNMEASentence(line)
+	if err != nil || len(fields[0]) < 3 {
+		return data.NoPosition, false
+	}
+
+	switch fields[0][len(fields[0])-3:] {
+	case "RMC":
+		if len(fields) > 9 {
+			date, err := time.Parse("020106", fields[9])
+			if err == nil {
+				p.date = date
+			}
+		}
+		if len(fields) > 8 {
+			p.speed = parseOptionalNMEAFloat(fields[7]) * knotsToMetresPerSecond
+			p.heading = parseOptionalNMEAFloat(fields[8])
+		}
+		return data.NoPosition, false
+	case "GSA":
+		if len(fields) > 2 {
+			fixType, err := strconv.Atoi(fields[2])
+			if err == nil {
+				p.fixType = data.FixType(fixType)
+			}
+		}
+		return data.NoPosition, false
+	case "GGA":
+		position, err := p.parseGGA(fields)
+		return position, err == nil
+	default:
+		return data.NoPosition, false
+	}
+}
+
+func (p *nmeaParser) parseGGA(fields []string) (data.Position, error) {
+	if len(fields) < 10 {
+		return data.NoPosition, fmt.Errorf("GGA sentence too short")
+	}
+	timestamp, err := p.parseTime(fields[1])
+	if err != nil {
+		return data.NoPosition, err
+	}
+	quality, err := strconv.Atoi(fields[6])
+	if err != nil {
+		return data.NoPosition, fmt.Errorf("invalid fix quality: %w", err)
+	}
+	if quality == 0 {
+		return data.Position{Timestamp: timestamp, FixType: data.NoFix}, nil
+	}
+
+	lat, err := parseNMEACoordinate(fields[2], fields[3], 2)
+	if err != nil {
+		return data.NoPosition, fmt.Errorf("invalid latitude: %w", err)
+	}
+	lon, err := parseNMEACoordinate(fields[4], fields[5], 3)
+	if err != nil {
+		return data.NoPosition, fmt.Errorf("invalid longitude: %w", err)
+	}
+	sats, err := strconv.Atoi(fields[7])
+	if err != nil {
+		return data.NoPosition, fmt.Errorf("invalid satellites: %w", err)
+	}
+
+	return data.Position{
+		Latitude:   lat,
+		Longitude:  lon,
+		Satellites: sats,
+		Timestamp:  timestamp,
+		Altitude:   parseOptionalNMEAFloat(fields[9]),
+		Speed:      p.speed,
+		Heading:    p.heading,
+		FixType:    p.fixType,
+		HDOP:       parseOptionalNMEAFloat(fields[8]),
+	}, nil
+}
+
+func (p *nmeaParser) parseTime(s string) (time.Time, error) {
+	if len(s) < 6 {
+		return time.Time{}, fmt.Errorf("invalid time: %s", s)
+	}
+	timeOfDay, err := time.Parse("150405", s[:6])
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time: %w", err)
+	}
+	var fraction time.Duration
+	if len(s) > 7 && s[6] == '.' {
+		value, err := strconv.ParseFloat("0"+s[6:], 64)
+		if err == nil {
+			fraction = time.Duration(value * float64(time.Second))
+		}
+	}
+
+	date := p.date
+	if date.IsZero() {
+		date = time.Now().UTC()
+	}
+	result := time.Date(date.Year(), date.Month(), date.Day(), timeOfDay.Hour(), timeOfDay.Minute(), timeOfDay.Second(), int(fraction), time.UTC)
+
+	// after midnight, the GGA sentences may arrive before the first RMC sentence with the new date
+	if !p.lastTimestamp.IsZero() && p.lastTimestamp.Sub(result) > 12*time.Hour {
+		result = result.AddDate(0, 0, 1)
+		if !p.date.IsZero() {
+			p.date = p.date.AddDate(0, 0, 1)
+		}
+	}
+	p.lastTimestamp = result
+	return result, nil
+}
+
+// splitNMEASentence verifies the checksum of the sentence, if available, and returns its fields.
+func splitNMEASentence(line string) ([]string, error) {
+	line = strings.TrimSpace(line)
+	if !strings.HasPrefix(line, "$") {
+		return nil, fmt.Errorf("invalid NMEA sentence: %s", line)
+	}
+	sentence, checksum, hasChecksum := strings.Cut(line[1:], "*")
+	if hasChecksum {
+		expected, err := strconv.ParseUint(checksum, 16, 8)
+		if err != nil {
+			return nil, fmt.Errorf("invalid checksum: %w", err)
+		}
+		var actual byte
+		for i := 0; i < len(sentence); i++ {
+			actual ^= sentence[i]
+		}
+		if byte(expected) != actual {
+			return nil, fmt.Errorf("checksum mismatch: %s", line)
+		}
+	}
+	return strings.Split(sentence, ","), nil
+}
+
+// parseOptionalNMEAFloat returns 0 for empty or invalid fields.
+func parseOptionalNMEAFloat(value string) float64 {
+	result, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0
+	}
+	return result
+}
+
+// parseNMEACoordinate converts the NMEA format (d)ddmm.mmmm into decimal degrees.
+func parseNMEACoordinate(value string, direction string, degreeDigits int) (float64, error) {
+	if len(value) < degreeDigits {
+		return 0, fmt.Errorf("coordinate too short: %s", value)
+	}
+	degrees, err := strconv.ParseFloat(value[:degreeDigits], 64)
+	if err != nil {
+		return 0, err
+	}
+	minutes, err := strconv.ParseFloat(value[degreeDigits:], 64)
+	if err != nil {
+		return 0, err
+	}
+
+	result := degrees + minutes/60
+	switch direction {
+	case "S", "W":
+		result = -result
+	}
+	return result, nil
+}
@@ -0,0 +1,128 @@
+package scanner
+
+import (
+	"context"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ftl/tetra-mess/pkg/data"
+)
+
+func TestReadNMEA(t *testing.T) {
+	tt := []struct {
+		name     string
+		lines    []string
+		expected []data.Position
+	}{
+		{
+			name: "position with date, speed and fix type",
+			lines: []string{
+				"$GPRMC,235958.00,A,5220.9931,N,01322.6631,E,10.0,90.0,311226,,,A*54",
+				"$GPGSA,A,3,04,05,,09,12,,,24,,,,,2.5,1.3,2.1*39",
+				"$GPGGA,235958.00,5220.9931,N,01322.6631,E,1,08,0.9,35.2,M,46.9,M,,*55",
+			},
+			expected: []data.Position{
+				{Latitude: 52.349885, Longitude: 13.377718, Satellites: 8, Timestamp: time.Date(2026, 12, 31, 23, 59, 58, 0, time.UTC), Altitude: 35.2, Speed: 10 * knotsToMetresPerSecond, Heading: 90, FixType: data.Fix3D, HDOP: 0.9},
+			},
+		},
+		{
+			name: "date rolls forward at midnight before the next RMC",
+			lines: []string{
+				"$GPRMC,235958.00,A,5220.9931,N,01322.6631,E,10.0,90.0,311226,,,A*54",
+				"$GPGGA,235959.50,5220.9932,N,01322.6700,E,1,08,0.9,35.2,M,46.9,M,,*51",
+				"$GPGGA,000000.50,5220.9933,N,01322.6800,E,1,08,0.9,35.2,M,46.9,M,,*5E",
+				"$GPGGA,000001.00,,,,,0,00,,,M,,M,,*49",
+				"$GPRMC,000001.00,A,5220.9933,N,01322.6800,E,10.0,90.0,010127,,,A*5B",
+				"$GPGGA,000002.00,5220.9934,N,01322.6900,E,2,09,0.8,35.0,M,46.9,M,,*5E",
+			},
+			expected: []data.Position{
+				{Latitude: 52.349887, Longitude: 13.377833, Satellites: 8, Timestamp: time.Date(2026, 12, 31, 23, 59, 59, 500000000, time.UTC)},
+				{Latitude: 52.349888, Longitude: 13.378000, Satellites: 8, Timestamp: time.Date(2027, 1, 1, 0, 0, 0, 500000000, time.UTC)},
+				{Timestamp: time.Date(2027, 1, 1, 0, 0, 1, 0, time.UTC), FixType: data.NoFix},
+				{Latitude: 52.349890, Longitude: 13.378167, Satellites: 9, Timestamp: time.Date(2027, 1, 1, 0, 0, 2, 0, time.UTC)},
+			},
+		},
+		{
+			name: "sentences with invalid checksum are ignored",
+			lines: []string{
+				"$GPRMC,235958.00,A,5220.9931,N,01322.6631,E,10.0,90.0,311226,,,A*54",
+				"$GPGGA,235958.00,5220.9931,N,01322.6631,E,1,08,0.9,35.2,M,46.9,M,,*56",
+				"garbage",
+				"$GPGGA,235959.50,5220.9932,N,01322.6700,E,1,08,0.9,35.2,M,46.9,M,,*51",
+			},
+			expected: []data.Position{
+				{Latitude: 52.349887, Longitude: 13.377833, Satellites: 8, Timestamp: time.Date(2026, 12, 31, 23, 59, 59, 500000000, time.UTC)},
+			},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var actual []data.Position
+			err := ReadNMEA(strings.NewReader(strings.Join(tc.lines, "\r\n")), func(position data.Position) {
+				actual = append(actual, position)
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertPositions(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestPacedBySentenceTime(t *testing.T) {
+	start := time.Date(2026, 10, 17, 8, 15, 2, 0, time.UTC)
+	var reported []time.Duration
+	began := time.Now()
+	report := pacedBySentenceTime(context.Background(), func(data.Position) {
+		reported = append(reported, time.Since(began))
+	})
+
+	for _, offset := range []time.Duration{0, 50 * time.Millisecond, 100 * time.Millisecond} {
+		report(data.Position{Timestamp: start.Add(offset)})
+	}
+
+	if len(reported) != 3 {
+		t.Fatalf("expected 3 positions, got %d", len(reported))
+	}
+	if reported[1] < 50*time.Millisecond || reported[2] < 100*time.Millisecond {
+		t.Errorf("the positions should be reported in the pace of their timestamps, got %v", reported)
+	}
+}
+
+// assertPositions compares the positions, the coordinates with a precision of about 0.1m. Speed, heading, altitude
+// and HDOP are only compared if they are expected.
+func assertPositions(t *testing.T, expected []data.Position, actual []data.Position) {
+	t.Helper()
+	if len(actual) != len(expected) {
+		t.Fatalf("expected %d positions, got %d: %v", len(expected), len(actual), actual)
+	}
+	for i, e := range expected {
+		a := actual[i]
+		if !a.Timestamp.Equal(e.Timestamp) {
+			t.Errorf("%d: expected timestamp %v, got %v", i, e.Timestamp, a.Timestamp)
+		}
+		if math.Abs(a.Latitude-e.Latitude) > 1e-6 || math.Abs(a.Longitude-e.Longitude) > 1e-6 {
+			t.Errorf("%d: expected coordinates %f,%f, got %f,%f", i, e.Latitude, e.Longitude, a.Latitude, a.Longitude)
+		}
+		if a.Satellites != e.Satellites {
+			t.Errorf("%d: expected %d satellites, got %d", i, e.Satellites, a.Satellites)
+		}
+		if e.FixType != data.UnknownFix && a.FixType != e.FixType {
+			t.Errorf("%d: expected fix type %v, got %v", i, e.FixType, a.FixType)
+		}
+		if e.Speed != 0 && math.Abs(a.Speed-e.Speed) > 1e-6 {
+			t.Errorf("%d: expected speed %f, got %f", i, e.Speed, a.Speed)
+		}
+		if e.Heading != 0 && a.Heading != e.Heading {
+			t.Errorf("%d: expected heading %f, got %f", i, e.Heading, a.Heading)
+		}
+		if e.Altitude != 0 && a.Altitude != e.Altitude {
+			t.Errorf("%d: expected altitude %f, got %f", i, e.Altitude, a.Altitude)
+		}
+		if e.HDOP != 0 && a.HDOP != e.HDOP {
+			t.Errorf("%d: expected HDOP %f, got %f", i, e.HDOP, a.HDOP)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package scanner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ftl/tetra-cli/pkg/radio"
+	"github.com/ftl/tetra-pei/ctrl"
+
+	"github.com/ftl/tetra-mess/pkg/data"
+)
+
+// MaxFixAge defines how long a fix from an external GPS receiver is considered as current position.
+const MaxFixAge = 5 * time.Second
+
+var ErrNoFix = errors.New("no current GPS fix")
+
+// PositionSource provides the current GPS position.
+type PositionSource interface {
+	RequestPosition(ctx context.Context, pei radio.PEI) (data.Position, error)
+}
+
+type PositionSourceFunc func(ctx context.Context, pei radio.PEI) (data.Position, error)
+
+func (f PositionSourceFunc) RequestPosition(ctx context.Context, pei radio.PEI) (data.Position, error) {
+	return f(ctx, pei)
+}
+
+// RadioPosition uses the internal GPS receiver of the radio.
+var RadioPosition PositionSource = PositionSourceFunc(RequestRadioPosition)
+
+// RequestRadioPosition reads the current position from the internal GPS receiver of the radio using AT+GPSPOS?
+// The radio does not report the kind of fix, so every position with satellites is taken as 2D fix.
+func RequestRadioPosition(ctx context.Context, pei radio.PEI) (data.Position, error) {
+	lat, lon, sats, timestamp, err := ctrl.RequestGPSPosition(ctx, pei)
+	if err != nil {
+		return data.NoPosition, err
+	}
+
+	fixType := data.NoFix
+	if sats > 0 {
+		fixType = data.Fix2D
+	}
+	return data.Position{
+		Latitude:   lat,
+		Longitude:  lon,
+		Satellites: sats,
+		Timestamp:  timestamp,
+		FixType:    fixType,
+	}, nil
+}
+
+// NewPositionSource creates the PositionSource for the given description:
+//   - radio: the internal GPS receiver of the radio
+//   - nmea:<device>[@<baudrate>]: a serial device or a file that provides a NMEA 0183 stream
+//   - gpsd[:<host>:<port>]: a gpsd instance, by default on localhost:2947
+//
+// The external GPS receivers fall back to the internal GPS receiver of the radio if they have no current fix.
+// An external receiver can be shared by several radios. Wrap the source with WithMotion for each radio to compute
+// speed and heading from its consecutive fixes.
+func NewPositionSource(ctx context.Context, description string, log Logger) (PositionSource, error) {
+	kind, address, _ := strings.Cut(description, ":")
+	switch strings.ToLower(kind) {
+	case "radio", "":
+		return RadioPosition, nil
+	case "nmea":
+		source, err := OpenNMEAPosition(ctx, address, log)
+		if err != nil {
+			return nil, err
+		}
+		return WithFallback(source, RadioPosition), nil
+	case "gpsd":
+		if address == "" {
+			address = DefaultGPSDAddress
+		}
+		return WithFallback(NewGPSDPosition(ctx, address, log), RadioPosition), nil
+	default:
+		return nil, fmt.Errorf("unknown position source: %s", description)
+	}
+}
+
+// WithFallback uses the fallback source if the primary source provides no position with GPS fix.
+func WithFallback(primary, fallback PositionSource) PositionSource {
+	return PositionSourceFunc(func(ctx context.Context, pei radio.PEI) (data.Position, error) {
+		position, err := primary.RequestPosition(ctx, pei)
+		if err == nil && position.HasFix() {
+			return position, nil
+		}
+		fallbackPosition, fallbackErr := fallback.RequestPosition(ctx, pei)
+		if fallbackErr != nil {
+			if err == nil {
+				return position, nil
+			}
+			return data.NoPosition, fmt.Errorf("%w, fallback: %w", err, fallbackErr)
+		}
+		return fallbackPosition, nil
+	})
+}
+
+// WithMotion computes speed and heading from the previous fix if the source does not provide them. The previous fix
+// is kept per wrapper, hence each radio needs its own wrapper.
+func WithMotion(source PositionSource) PositionSource {
+	mutex := &sync.Mutex{}
+	var previous data.Position
+	return PositionSourceFunc(func(ctx context.Context, pei radio.PEI) (data.Position, error) {
+		position, err := source.RequestPosition(ctx, pei)
+		if err != nil || !position.HasFix() {
+			return position, err
+		}
+
+		mutex.Lock()
+		defer mutex.Unlock()
+		if position.Timestamp.Equal(previous.Timestamp) {
+			return previous, nil
+		}
+		position = position.WithMotion(previous)
+		if position.Timestamp.After(previous.Timestamp) {
+			previous = position
+		}
+		return position, nil
+	})
+}
+
+// latestFix keeps the latest fix received from an external GPS receiver.
+type latestFix struct {
+	mutex    sync.Mutex
+	position data.Position
+	received time.Time
+}
+
+func (f *latestFix) Update(position data.Position) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.position = position
+	f.received = time.Now()
+}
+
+func (f *latestFix) RequestPosition(context.Context, radio.PEI) (data.Position, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if f.received.IsZero() || time.Since(f.received) > MaxFixAge {
+		return data.NoPosition, ErrNoFix
+	}
+	return f.position, nil
+}
@@ -0,0 +1,31 @@
+package scanner
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ftl/tetra-mess/pkg/data"
+)
+
+func TestRequestRadioPositionSetsFixType(t *testing.T) {
+	tt := []struct {
+		name     string
+		response string
+		expected data.FixType
+	}{
+		{name: "with satellites", response: "+GPSPOS: 08:15:02,N: 52_20.9931,E: 013_22.6631,7", expected: data.Fix2D},
+		{name: "without satellites", response: "+GPSPOS: 08:15:02,N: 52_20.9931,E: 013_22.6631,0", expected: data.NoFix},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			pei := &scriptedPEI{responses: map[string][][]string{"AT+GPSPOS?": {{tc.response}}}}
+			position, err := RequestRadioPosition(context.Background(), pei)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if position.FixType != tc.expected {
+				t.Errorf("expected fix type %s, got %s", tc.expected, position.FixType)
+			}
+		})
+	}
+}
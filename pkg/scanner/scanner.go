@@ -2,6 +2,7 @@ package scanner
 
 import (
 	"context"
+	"slices"
 	"time"
 
 	"github.com/ftl/tetra-cli/pkg/radio"
@@ -13,60 +14,85 @@ import (
 
 type Logger func(string, ...any)
 
+// Device is a radio that is used for measurements. The name identifies the device in the data points.
+type Device struct {
+	Name string
+	PEI  radio.PEI
+}
+
 type DataPoint struct {
+	Device      string
 	Position    data.Position
 	Measurement quality.Measurement
 }
 
-func ScanSignalAndPosition(ctx context.Context, pei radio.PEI, log Logger) (data.Position, []data.DataPoint) {
-	lat, lon, sats, timestamp, err := ctrl.RequestGPSPosition(ctx, pei)
+// ScanSignalAndPosition reads the position and the cell list. The serving cell is the cell that the cell list
+// reports as serving. If the cell list does not report a serving cell, the first cell with the LAC from AT+CREG? is
+// marked as serving.
+func ScanSignalAndPosition(ctx context.Context, pei radio.PEI, positionSource PositionSource, cellList CellListProvider, log Logger) (data.Position, []data.DataPoint) {
+	position := RequestPosition(ctx, pei, positionSource, log)
+
+	cellInfos, err := cellList.RequestCellList(ctx, pei)
 	if err != nil {
-		log("cannot read GPS position: %v", err)
-		lat = 0
-		lon = 0
-		sats = 0
-		timestamp = time.Now().UTC()
+		log("cannot read cell list information: %v", err)
+		return position, []data.DataPoint{servingCellDataPoint(ctx, pei, position, log)}
 	}
 
-	position := data.Position{
-		Latitude:   lat,
-		Longitude:  lon,
-		Satellites: sats,
-		Timestamp:  timestamp,
+	servingFound := slices.ContainsFunc(cellInfos, func(cellInfo data.CellInfo) bool {
+		return cellInfo.Serving
+	})
+	var servingLAC uint32
+	if !servingFound {
+		servingLAC, err = RequestServingLAC(ctx, pei)
+		if err != nil {
+			log("cannot read serving cell: %v", err)
+			servingLAC = 0
+		}
 	}
 
+	dataPoints := make([]data.DataPoint, 0, len(cellInfos))
+	for _, cellInfo := range cellInfos {
+		serving := cellInfo.Serving || (!servingFound && servingLAC != 0 && cellInfo.LAC == servingLAC)
+		servingFound = servingFound || serving
+		dataPoint := data.NewDataPoint(position)
+		dataPoint.LAC = cellInfo.LAC
+		dataPoint.Carrier = cellInfo.Carrier
+		dataPoint.RSSI = cellInfo.RSSI
+		dataPoint.Cx = cellInfo.Cx
+		dataPoint.Serving = serving
+		dataPoints = append(dataPoints, dataPoint)
+	}
+	return position, dataPoints
+}
+
+// servingCellDataPoint reads the LAC and the signal strength of the serving cell, if the cell list is not available.
+func servingCellDataPoint(ctx context.Context, pei radio.PEI, position data.Position, log Logger) data.DataPoint {
 	dbm, err := ctrl.RequestSignalStrength(ctx, pei)
 	if err != nil {
 		log("cannot read signal strength: %v", err)
 		dbm = 0
 	}
 
-	cellInfos, err := RequestCellListInformation(ctx, pei)
+	servingLAC, err := RequestServingLAC(ctx, pei)
 	if err != nil {
-		log("cannot read cell list information: %v", err)
-		return position,
-			[]data.DataPoint{{
-				Latitude:   lat,
-				Longitude:  lon,
-				Satellites: sats,
-				Timestamp:  timestamp,
-				RSSI:       dbm,
-			}}
+		log("cannot read serving cell: %v", err)
+		servingLAC = 0
 	}
 
-	dataPoints := make([]data.DataPoint, 0, len(cellInfos))
-	for _, cellInfo := range cellInfos {
-		dataPoint := data.DataPoint{
-			Latitude:   lat,
-			Longitude:  lon,
-			Satellites: sats,
-			Timestamp:  timestamp,
-			LAC:        cellInfo.LAC,
-			Carrier:    cellInfo.Carrier,
-			RSSI:       cellInfo.RSSI,
-			Cx:         cellInfo.Cx,
-		}
-		dataPoints = append(dataPoints, dataPoint)
+	result := data.NewDataPoint(position)
+	result.LAC = servingLAC
+	result.RSSI = dbm
+	result.Serving = servingLAC != 0
+	return result
+}
+
+// RequestPosition reads the current GPS position from the given source. If the position cannot be read, the
+// result has no satellites and the current time as timestamp.
+func RequestPosition(ctx context.Context, pei radio.PEI, source PositionSource, log Logger) data.Position {
+	position, err := source.RequestPosition(ctx, pei)
+	if err != nil {
+		log("cannot read GPS position: %v", err)
+		return data.Position{Timestamp: time.Now().UTC()}
 	}
-	return position, dataPoints
+	return position
 }
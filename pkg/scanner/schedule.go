@@ -0,0 +1,152 @@
+package scanner
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ftl/tetra-mess/pkg/data"
+)
+
+type ScanMode string
+
+const (
+	TimeScanMode     ScanMode = "time"
+	DistanceScanMode ScanMode = "distance"
+	AdaptiveScanMode ScanMode = "adaptive"
+)
+
+const (
+	// PositionPollInterval is used to check the position if the schedule depends on the movement. With the radio's
+	// GPS, this costs one AT+GPSPOS? per interval, the scan itself reuses the polled position.
+	PositionPollInterval = 1 * time.Second
+	// MinAdaptiveScanInterval limits the scan rate of the adaptive schedule at high speed.
+	MinAdaptiveScanInterval = 2 * time.Second
+)
+
+// ScanSchedule decides when the next scan is due.
+type ScanSchedule interface {
+	// PollInterval defines how often the schedule is asked if a scan is due.
+	PollInterval() time.Duration
+	// PositionRequired indicates if the schedule needs the current position to decide if a scan is due.
+	PositionRequired() bool
+	// ScanDue decides if a scan is due at the given position. If no position is required, it is NoPosition.
+	ScanDue(position data.Position) bool
+	// Scanned tells the schedule that a scan was done at the given position.
+	Scanned(position data.Position)
+}
+
+// NewScanSchedule creates the schedule for the given mode. The interval is used by the time-based schedule, the maximum
+// interval by the distance-based and the adaptive schedule. The distance is given in metres.
+func NewScanSchedule(mode ScanMode, interval time.Duration, maxInterval time.Duration, distance float64) (ScanSchedule, error) {
+	switch ScanMode(strings.ToLower(string(mode))) {
+	case TimeScanMode, "":
+		return &TimeSchedule{Interval: interval}, nil
+	case DistanceScanMode:
+		return &DistanceSchedule{Distance: distance, MaxInterval: maxInterval}, nil
+	case AdaptiveScanMode:
+		return &AdaptiveSchedule{Distance: distance, MinInterval: MinAdaptiveScanInterval, MaxInterval: maxInterval}, nil
+	default:
+		return nil, fmt.Errorf("unknown scan mode: %s", mode)
+	}
+}
+
+// TimeSchedule scans in a fixed time interval.
+type TimeSchedule struct {
+	Interval time.Duration
+}
+
+func (s *TimeSchedule) PollInterval() time.Duration {
+	return s.Interval
+}
+
+func (s *TimeSchedule) PositionRequired() bool {
+	return false
+}
+
+func (s *TimeSchedule) ScanDue(data.Position) bool {
+	return true
+}
+
+func (s *TimeSchedule) Scanned(data.Position) {}
+
+// DistanceSchedule scans every time the given distance in metres was travelled since the last scan.
+// It scans at least in the maximum interval, also without a GPS fix or when standing still. The maximum interval
+// should be much longer than the interval of the time-based schedule, otherwise it scans just as often while parked.
+type DistanceSchedule struct {
+	Distance    float64
+	MaxInterval time.Duration
+
+	lastScan     data.Position
+	lastScanTime time.Time
+}
+
+func (s *DistanceSchedule) PollInterval() time.Duration {
+	return PositionPollInterval
+}
+
+func (s *DistanceSchedule) PositionRequired() bool {
+	return true
+}
+
+func (s *DistanceSchedule) ScanDue(position data.Position) bool {
+	if time.Since(s.lastScanTime) >= s.MaxInterval {
+		return true
+	}
+	if !position.HasFix() || !s.lastScan.HasFix() {
+		return false
+	}
+	return s.lastScan.DistanceTo(position) >= s.Distance
+}
+
+func (s *DistanceSchedule) Scanned(position data.Position) {
+	s.lastScan = position
+	s.lastScanTime = time.Now()
+}
+
+// AdaptiveSchedule adapts the scan interval to the current speed, so that a scan is done about every given distance
+// in metres. The interval is kept between the minimum and the maximum interval.
+type AdaptiveSchedule struct {
+	Distance    float64
+	MinInterval time.Duration
+	MaxInterval time.Duration
+
+	lastPosition data.Position
+	speed        float64
+	lastScanTime time.Time
+}
+
+func (s *AdaptiveSchedule) PollInterval() time.Duration {
+	return PositionPollInterval
+}
+
+func (s *AdaptiveSchedule) PositionRequired() bool {
+	return true
+}
+
+func (s *AdaptiveSchedule) ScanDue(position data.Position) bool {
+	if position.HasFix() && s.lastPosition.HasFix() {
+		speed, ok := s.lastPosition.SpeedTo(position)
+		if ok {
+			s.speed = speed
+		}
+	}
+	if position.HasFix() {
+		s.lastPosition = position
+	}
+
+	return time.Since(s.lastScanTime) >= s.Interval()
+}
+
+// Interval returns the scan interval for the current speed.
+func (s *AdaptiveSchedule) Interval() time.Duration {
+	if s.speed <= 0 {
+		return s.MaxInterval
+	}
+	interval := time.Duration(s.Distance / s.speed * float64(time.Second))
+	return max(s.MinInterval, min(s.MaxInterval, interval))
+}
+
+func (s *AdaptiveSchedule) Scanned(data.Position) {
+	s.lastScanTime = time.Now()
+}
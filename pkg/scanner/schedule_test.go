@@ -0,0 +1,60 @@
+package scanner
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ftl/tetra-mess/pkg/data"
+)
+
+func TestScheduleNotDueWhileParked(t *testing.T) {
+	start := time.Now().Add(-time.Minute)
+	parked := func(seconds int) data.Position {
+		return data.Position{Latitude: 52.349885, Longitude: 13.377718, Satellites: 7, Timestamp: start.Add(time.Duration(seconds) * time.Second)}
+	}
+	for _, mode := range []ScanMode{DistanceScanMode, AdaptiveScanMode} {
+		t.Run(string(mode), func(t *testing.T) {
+			schedule, err := NewScanSchedule(mode, 10*time.Millisecond, time.Hour, 100)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !schedule.ScanDue(parked(0)) {
+				t.Fatal("the first scan should be due immediately")
+			}
+			schedule.Scanned(parked(0))
+
+			time.Sleep(20 * time.Millisecond)
+			for i := 1; i <= 10; i++ {
+				if schedule.ScanDue(parked(i)) {
+					t.Fatalf("no scan should be due while the position stays the same, got one after %d polls", i)
+				}
+			}
+		})
+	}
+}
+
+func TestDistanceScheduleDueAfterDistance(t *testing.T) {
+	schedule, err := NewScanSchedule(DistanceScanMode, 10*time.Second, time.Hour, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	schedule.Scanned(data.Position{Latitude: 52.000, Longitude: 13.0, Satellites: 7})
+
+	tt := []struct {
+		name     string
+		position data.Position
+		expected bool
+	}{
+		{name: "less than the distance", position: data.Position{Latitude: 52.0005, Longitude: 13.0, Satellites: 7}, expected: false},
+		{name: "more than the distance", position: data.Position{Latitude: 52.0010, Longitude: 13.0, Satellites: 7}, expected: true},
+		{name: "no fix", position: data.Position{Latitude: 52.0010, Longitude: 13.0}, expected: false},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			actual := schedule.ScanDue(tc.position)
+			if actual != tc.expected {
+				t.Errorf("expected %t, got %t", tc.expected, actual)
+			}
+		})
+	}
+}
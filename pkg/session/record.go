@@ -0,0 +1,103 @@
+package session
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ftl/tetra-cli/pkg/radio"
+)
+
+// The session file contains one line per event. Each line starts with the timestamp, followed by a marker
+// and the content. The request, the response, and the result of an exchange are written together when the exchange
+// is completed, so concurrent requests never interleave in the session file.
+const (
+	requestMarker             = ">"
+	responseMarker            = "<"
+	okMarker                  = "="
+	errorMarker               = "!"
+	indicationMarker          = "*"
+	indicationContinuedMarker = "~"
+	timestampLayout           = "2006-01-02T15:04:05.000000Z07:00"
+)
+
+// RecordingPEI wraps a PEI and writes every request, its response, and all indications with timestamps to
+// a session file.
+type RecordingPEI struct {
+	radio.PEI
+
+	mutex sync.Mutex
+	out   io.Writer
+}
+
+func NewRecorder(pei radio.PEI, out io.Writer) *RecordingPEI {
+	return &RecordingPEI{
+		PEI: pei,
+		out: out,
+	}
+}
+
+// Unwrap returns the recorded PEI.
+func (p *RecordingPEI) Unwrap() radio.PEI {
+	return p.PEI
+}
+
+func (p *RecordingPEI) AddIndication(prefix string, trailingLines int, handler func(lines []string)) error {
+	return p.PEI.AddIndication(prefix, trailingLines, func(lines []string) {
+		timestamp := time.Now()
+		var record strings.Builder
+		writeRecordLines(&record, timestamp, indicationMarker, lines[:1]...)
+		writeRecordLines(&record, timestamp, indicationContinuedMarker, lines[1:]...)
+		p.write(record.String())
+		handler(lines)
+	})
+}
+
+func (p *RecordingPEI) Request(ctx context.Context, request string) ([]string, error) {
+	return p.AT(ctx, request)
+}
+
+func (p *RecordingPEI) AT(ctx context.Context, request string) ([]string, error) {
+	requested := time.Now()
+	response, err := p.PEI.AT(ctx, request)
+	completed := time.Now()
+
+	var record strings.Builder
+	writeRecordLines(&record, requested, requestMarker, request)
+	writeRecordLines(&record, completed, responseMarker, response...)
+	if err != nil {
+		writeRecordLines(&record, completed, errorMarker, err.Error())
+	} else {
+		writeRecordLines(&record, completed, okMarker, "")
+	}
+	p.write(record.String())
+
+	return response, err
+}
+
+func (p *RecordingPEI) ATs(ctx context.Context, requests ...string) error {
+	for _, request := range requests {
+		_, err := p.AT(ctx, request)
+		if err != nil {
+			return fmt.Errorf("%s failed: %w", request, err)
+		}
+	}
+	return nil
+}
+
+// write writes one complete record to the session file.
+func (p *RecordingPEI) write(record string) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	io.WriteString(p.out, record)
+}
+
+func writeRecordLines(record *strings.Builder, timestamp time.Time, marker string, lines ...string) {
+	for _, line := range lines {
+		fmt.Fprintf(record, "%s %s %s\n", timestamp.UTC().Format(timestampLayout), marker, line)
+	}
+}
@@ -0,0 +1,289 @@
+package session
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+type exchange struct {
+	timestamp time.Time
+	completed time.Time
+	request   string
+	response  []string
+	err       string
+}
+
+type indication struct {
+	timestamp time.Time
+	lines     []string
+}
+
+type indicationHandler struct {
+	prefix        string
+	trailingLines int
+	handler       func(lines []string)
+}
+
+// ReplayPEI feeds the responses of a recorded session back to the application. Each request is answered with the
+// response to the next recorded request with the same content. All indications that were recorded before this request
+// are handled first. With a speed factor > 0, the replay keeps the recorded timing, accelerated by the given factor.
+type ReplayPEI struct {
+	speed       float64
+	exchanges   []exchange
+	indications []indication
+
+	mutex              sync.Mutex
+	handlers           []indicationHandler
+	cursor             int
+	indicationCursor   int
+	start              time.Time
+	closed             chan struct{}
+	disconnectCallback func()
+}
+
+func NewReplay(in io.Reader, speed float64) (*ReplayPEI, error) {
+	exchanges, indications, err := readSession(in)
+	if err != nil {
+		return nil, err
+	}
+	if len(exchanges) == 0 {
+		return nil, fmt.Errorf("the session contains no requests")
+	}
+
+	return &ReplayPEI{
+		speed:       speed,
+		exchanges:   exchanges,
+		indications: indications,
+		closed:      make(chan struct{}),
+	}, nil
+}
+
+func readSession(in io.Reader) ([]exchange, []indication, error) {
+	exchanges := make([]exchange, 0)
+	indications := make([]indication, 0)
+	var currentExchange *exchange
+	var currentIndication *indication
+
+	lineScanner := bufio.NewScanner(in)
+	lineNumber := 0
+	for lineScanner.Scan() {
+		lineNumber++
+		line := strings.TrimRight(lineScanner.Text(), "\r\n")
+		if line == "" {
+			continue
+		}
+		parts := strings.SplitN(line, " ", 3)
+		if len(parts) < 2 {
+			return nil, nil, fmt.Errorf("invalid record in line %d: %s", lineNumber, line)
+		}
+		timestamp, err := time.Parse(timestampLayout, parts[0])
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid timestamp in line %d: %w", lineNumber, err)
+		}
+		var content string
+		if len(parts) == 3 {
+			content = parts[2]
+		}
+
+		switch parts[1] {
+		case requestMarker:
+			exchanges = append(exchanges, exchange{timestamp: timestamp, completed: timestamp, request: content})
+			currentExchange = &exchanges[len(exchanges)-1]
+		case responseMarker:
+			if currentExchange == nil {
+				return nil, nil, fmt.Errorf("response without request in line %d", lineNumber)
+			}
+			currentExchange.response = append(currentExchange.response, content)
+			currentExchange.completed = timestamp
+		case okMarker:
+			if currentExchange != nil {
+				currentExchange.completed = timestamp
+			}
+			currentExchange = nil
+		case errorMarker:
+			if currentExchange == nil {
+				return nil, nil, fmt.Errorf("error without request in line %d", lineNumber)
+			}
+			currentExchange.err = content
+			currentExchange.completed = timestamp
+			currentExchange = nil
+		case indicationMarker:
+			indications = append(indications, indication{timestamp: timestamp, lines: []string{content}})
+			currentIndication = &indications[len(indications)-1]
+		case indicationContinuedMarker:
+			if currentIndication == nil {
+				return nil, nil, fmt.Errorf("indication line without indication in line %d", lineNumber)
+			}
+			currentIndication.lines = append(currentIndication.lines, content)
+		default:
+			return nil, nil, fmt.Errorf("unknown marker in line %d: %s", lineNumber, parts[1])
+		}
+	}
+
+	return exchanges, indications, lineScanner.Err()
+}
+
+func (p *ReplayPEI) Close() {
+	p.mutex.Lock()
+	select {
+	case <-p.closed:
+		p.mutex.Unlock()
+		return
+	default:
+		close(p.closed)
+	}
+	disconnectCallback := p.disconnectCallback
+	p.mutex.Unlock()
+
+	if disconnectCallback != nil {
+		disconnectCallback()
+	}
+}
+
+func (p *ReplayPEI) Closed() bool {
+	select {
+	case <-p.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+func (p *ReplayPEI) WaitUntilClosed(ctx context.Context) {
+	select {
+	case <-p.closed:
+	case <-ctx.Done():
+	}
+}
+
+func (p *ReplayPEI) OnDisconnect(callback func()) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.disconnectCallback = callback
+}
+
+func (p *ReplayPEI) AddIndication(prefix string, trailingLines int, handler func(lines []string)) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.handlers = append(p.handlers, indicationHandler{
+		prefix:        strings.ToUpper(prefix),
+		trailingLines: trailingLines,
+		handler:       handler,
+	})
+	return nil
+}
+
+func (p *ReplayPEI) ClearSyntaxErrors(ctx context.Context) error {
+	return nil
+}
+
+func (p *ReplayPEI) Request(ctx context.Context, request string) ([]string, error) {
+	return p.AT(ctx, request)
+}
+
+func (p *ReplayPEI) AT(ctx context.Context, request string) ([]string, error) {
+	if p.Closed() {
+		return nil, errors.New("replay finished")
+	}
+
+	next, ok := p.nextExchange(request)
+	if !ok {
+		if p.finished() {
+			p.Close()
+			return nil, errors.New("replay finished")
+		}
+		return nil, fmt.Errorf("request %s not found in the recorded session", request)
+	}
+
+	err := p.waitFor(ctx, next.timestamp)
+	if err != nil {
+		return nil, err
+	}
+	p.handleIndicationsBefore(next.completed)
+
+	if next.err != "" {
+		return nil, errors.New(next.err)
+	}
+	return next.response, nil
+}
+
+func (p *ReplayPEI) ATs(ctx context.Context, requests ...string) error {
+	for _, request := range requests {
+		_, err := p.AT(ctx, request)
+		if err != nil {
+			return fmt.Errorf("%s failed: %w", request, err)
+		}
+	}
+	return nil
+}
+
+func (p *ReplayPEI) nextExchange(request string) (exchange, bool) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.start.IsZero() {
+		p.start = time.Now()
+	}
+	for i := p.cursor; i < len(p.exchanges); i++ {
+		if strings.EqualFold(p.exchanges[i].request, request) {
+			p.cursor = i + 1
+			return p.exchanges[i], true
+		}
+	}
+	return exchange{}, false
+}
+
+func (p *ReplayPEI) finished() bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.cursor >= len(p.exchanges)
+}
+
+// waitFor waits until the replay time reaches the recorded timestamp.
+func (p *ReplayPEI) waitFor(ctx context.Context, timestamp time.Time) error {
+	if p.speed <= 0 {
+		return nil
+	}
+
+	offset := timestamp.Sub(p.exchanges[0].timestamp)
+	due := p.start.Add(time.Duration(float64(offset) / p.speed))
+	delay := time.Until(due)
+	if delay <= 0 {
+		return nil
+	}
+
+	select {
+	case <-time.After(delay):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-p.closed:
+		return errors.New("replay closed")
+	}
+}
+
+func (p *ReplayPEI) handleIndicationsBefore(timestamp time.Time) {
+	p.mutex.Lock()
+	due := make([]indication, 0)
+	for p.indicationCursor < len(p.indications) && !p.indications[p.indicationCursor].timestamp.After(timestamp) {
+		due = append(due, p.indications[p.indicationCursor])
+		p.indicationCursor++
+	}
+	handlers := p.handlers
+	p.mutex.Unlock()
+
+	for _, indication := range due {
+		for _, handler := range handlers {
+			if strings.HasPrefix(strings.ToUpper(indication.lines[0]), handler.prefix) {
+				handler.handler(indication.lines)
+				break
+			}
+		}
+	}
+}
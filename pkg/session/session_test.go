@@ -0,0 +1,162 @@
+package session
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ftl/tetra-cli/pkg/radio"
+)
+
+type fakeResponse struct {
+	lines []string
+	err   error
+	// started is closed when the request is sent to the radio
+	started chan struct{}
+	// wait blocks the request until it is closed
+	wait chan struct{}
+}
+
+// fakePEI answers requests with scripted responses and can emit indications.
+type fakePEI struct {
+	radio.PEI
+
+	responses map[string]fakeResponse
+	mutex     sync.Mutex
+	handlers  []indicationHandler
+}
+
+func (p *fakePEI) AddIndication(prefix string, trailingLines int, handler func(lines []string)) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.handlers = append(p.handlers, indicationHandler{prefix: prefix, trailingLines: trailingLines, handler: handler})
+	return nil
+}
+
+func (p *fakePEI) AT(ctx context.Context, request string) ([]string, error) {
+	response, ok := p.responses[request]
+	if !ok {
+		return nil, errors.New("unknown request")
+	}
+	if response.started != nil {
+		close(response.started)
+	}
+	if response.wait != nil {
+		<-response.wait
+	}
+	return response.lines, response.err
+}
+
+func (p *fakePEI) indicate(lines ...string) {
+	p.mutex.Lock()
+	handlers := p.handlers
+	p.mutex.Unlock()
+	for _, handler := range handlers {
+		if strings.HasPrefix(lines[0], handler.prefix) {
+			handler.handler(lines)
+		}
+	}
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	pei := &fakePEI{responses: map[string]fakeResponse{
+		"AT+GCLI?": {lines: []string{"+GCLI: 1", "1,2,3"}},
+		"AT+CREG?": {lines: []string{"+CREG: 0,1,1234"}},
+		"AT+CSQ":   {err: errors.New("+CME ERROR: 4")},
+	}}
+	session := &bytes.Buffer{}
+	recorder := NewRecorder(pei, session)
+	var recordedIndications [][]string
+	err := recorder.AddIndication("+CREG:", 1, func(lines []string) {
+		recordedIndications = append(recordedIndications, lines)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	recorder.AT(ctx, "AT+GCLI?")
+	pei.indicate("+CREG: 1,5678", "trailing")
+	recorder.AT(ctx, "AT+CREG?")
+	recorder.AT(ctx, "AT+CSQ")
+	recorder.AT(ctx, "AT+GCLI?")
+	if len(recordedIndications) != 1 {
+		t.Fatalf("indication was not passed to the handler while recording")
+	}
+
+	replay, err := NewReplay(strings.NewReader(session.String()), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var replayedIndications [][]string
+	replay.AddIndication("+CREG:", 1, func(lines []string) {
+		replayedIndications = append(replayedIndications, lines)
+	})
+
+	response, err := replay.AT(ctx, "AT+GCLI?")
+	if err != nil || !slices.Equal(response, []string{"+GCLI: 1", "1,2,3"}) {
+		t.Errorf("unexpected response to the first AT+GCLI?: %v, %v", response, err)
+	}
+	if len(replayedIndications) != 0 {
+		t.Errorf("indication replayed too early")
+	}
+	response, err = replay.AT(ctx, "AT+CREG?")
+	if err != nil || !slices.Equal(response, []string{"+CREG: 0,1,1234"}) {
+		t.Errorf("unexpected response to AT+CREG?: %v, %v", response, err)
+	}
+	if len(replayedIndications) != 1 || !slices.Equal(replayedIndications[0], []string{"+CREG: 1,5678", "trailing"}) {
+		t.Errorf("unexpected replayed indications: %v", replayedIndications)
+	}
+	_, err = replay.AT(ctx, "AT+CSQ")
+	if err == nil || err.Error() != "+CME ERROR: 4" {
+		t.Errorf("unexpected error of AT+CSQ: %v", err)
+	}
+	response, err = replay.AT(ctx, "AT+GCLI?")
+	if err != nil || !slices.Equal(response, []string{"+GCLI: 1", "1,2,3"}) {
+		t.Errorf("unexpected response to the second AT+GCLI?: %v, %v", response, err)
+	}
+	_, err = replay.AT(ctx, "AT+GCLI?")
+	if err == nil || !replay.Closed() {
+		t.Errorf("the replay should be finished")
+	}
+}
+
+func TestRecordConcurrentRequests(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	pei := &fakePEI{responses: map[string]fakeResponse{
+		"AT+SLOW": {lines: []string{"slow response"}, started: started, wait: release},
+		"AT+FAST": {lines: []string{"fast response"}},
+	}}
+	session := &bytes.Buffer{}
+	recorder := NewRecorder(pei, session)
+	ctx := context.Background()
+
+	slowDone := make(chan struct{})
+	go func() {
+		recorder.AT(ctx, "AT+SLOW")
+		close(slowDone)
+	}()
+	<-started
+	recorder.AT(ctx, "AT+FAST")
+	close(release)
+	<-slowDone
+
+	exchanges, _, err := readSession(strings.NewReader(session.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(exchanges) != 2 {
+		t.Fatalf("expected 2 exchanges, got %d", len(exchanges))
+	}
+	for _, exchange := range exchanges {
+		expected := strings.ToLower(strings.TrimPrefix(exchange.request, "AT+")) + " response"
+		if !slices.Equal(exchange.response, []string{expected}) {
+			t.Errorf("%s got the wrong response: %v", exchange.request, exchange.response)
+		}
+	}
+}
@@ -7,12 +7,15 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/ftl/tetra-cli/pkg/radio"
 
+	"github.com/ftl/tetra-mess/pkg/connection"
 	"github.com/ftl/tetra-mess/pkg/data"
+	"github.com/ftl/tetra-mess/pkg/handover"
 	"github.com/ftl/tetra-mess/pkg/scanner"
 )
 
@@ -20,55 +23,107 @@ type UI interface {
 	Send(msg tea.Msg)
 }
 
+// MetadataCollector collects the metadata for the trace files. It is called once the radios are initialized.
+type MetadataCollector func(ctx context.Context, log scanner.Logger) data.Metadata
+
 type App struct {
-	ui    UI
-	radio *radio.Radio
+	ui     UI
+	radios []*radio.Radio
+	loops  []*scanner.ScanLoop
 
 	do        chan func() error
 	radioData chan scanner.DataPoint
+	outages   chan deviceOutage
+
+	outputDir       string
+	outputFormat    string
+	collectMetadata MetadataCollector
+	metadata        data.Metadata
+	traceFile       io.WriteCloser
+	handoverFile    io.WriteCloser
+	handovers       *handover.Detector
+}
 
-	outputDir    string
-	outputFormat string
-	traceFile    io.WriteCloser
+type deviceOutage struct {
+	connection.Outage
+	device string
 }
 
-func NewApp(ctx context.Context, ui UI, pei radio.PEI, outputDir, outputFormat string, scanInterval, scanTimeout time.Duration) (*App, error) {
+func NewApp(ctx context.Context, ui UI, devices []scanner.Device, newScanLoop scanner.ScanLoopFactory, outputDir, outputFormat string, collectMetadata MetadataCollector) (*App, error) {
 	result := &App{
-		ui:           ui,
-		do:           make(chan func() error),
-		radioData:    make(chan scanner.DataPoint, 1),
-		outputDir:    outputDir,
-		outputFormat: strings.ToLower(outputFormat),
-		traceFile:    nil,
+		ui:              ui,
+		do:              make(chan func() error),
+		radioData:       make(chan scanner.DataPoint, len(devices)),
+		outages:         make(chan deviceOutage, len(devices)),
+		outputDir:       outputDir,
+		outputFormat:    strings.ToLower(outputFormat),
+		collectMetadata: collectMetadata,
+		traceFile:       nil,
+		handovers:       handover.NewDetector(),
 	}
 
-	radioLog := func(format string, args ...any) {
-		timestamp := fmt.Sprintf("[%s] ", time.Now().Format(time.TimeOnly))
-		ui.Send(fmt.Sprintf(timestamp+format, args...))
-	}
-	loop := scanner.NewScanLoop(scanInterval, scanTimeout, result.radioData, radioLog)
+	connected := atomic.Int32{}
+	connected.Store(int32(len(devices)))
+	for _, device := range devices {
+		radioLog := func(format string, args ...any) {
+			timestamp := fmt.Sprintf("[%s] ", time.Now().Format(time.TimeOnly))
+			if len(devices) > 1 {
+				timestamp += fmt.Sprintf("%s: ", device.Name)
+			}
+			ui.Send(fmt.Sprintf(timestamp+format, args...))
+		}
+		loop := newScanLoop(device.Name, result.radioData, radioLog)
+
+		if supervised, ok := connection.AsSupervised(device.PEI); ok {
+			supervised.SetLogger(radioLog)
+			supervised.OnOutage(func(outage connection.Outage) {
+				select {
+				case result.outages <- deviceOutage{device: device.Name, Outage: outage}:
+				case <-ctx.Done():
+				}
+			})
+		}
 
-	radio, err := radio.Open(ctx, pei, nil)
-	if err != nil {
-		return nil, fmt.Errorf("cannot setup radio: %v", err)
+		radio, err := radio.Open(ctx, device.PEI, nil)
+		if err != nil {
+			result.closeRadios()
+			return nil, fmt.Errorf("cannot setup radio %s: %v", device.Name, err)
+		}
+		radio.OnDisconnect(func() {
+			if connected.Add(-1) > 0 {
+				radioLog("connection closed")
+				return
+			}
+			ui.Send(ConnectionClosed{})
+		})
+		result.radios = append(result.radios, radio)
+		result.loops = append(result.loops, loop)
 	}
-	radio.OnDisconnect(func() {
-		ui.Send(ConnectionClosed{})
-	})
-	radio.RunLoop(loop.Run)
-	result.radio = radio
 
 	return result, nil
 }
 
+func (a *App) closeRadios() {
+	for _, radio := range a.radios {
+		radio.Close()
+	}
+}
+
+// Start collects the metadata from the initialized radios, starts the scan loops, and handles the events of the app
+// in its own goroutine.
 func (a *App) Start(ctx context.Context) {
 	go func() {
 		defer a.stopTrace()
 		defer func() {
 			fmt.Println("Closing radio connection...")
-			a.radio.Close()
+			a.closeRadios()
 		}()
 
+		a.metadata = a.collectMetadata(ctx, a.showMessage)
+		for i, radio := range a.radios {
+			radio.RunLoop(a.loops[i].Run)
+		}
+
 		a.ui.Send(a)
 		for {
 			select {
@@ -83,7 +138,10 @@ func (a *App) Start(ctx context.Context) {
 				}
 			case rd := <-a.radioData:
 				a.traceRadioData(RadioData(rd))
+				a.traceHandovers(a.handovers.Add(rd.Measurement))
 				a.ui.Send(RadioData(rd))
+			case outage := <-a.outages:
+				a.traceOutage(outage)
 			}
 		}
 	}()
@@ -121,13 +179,14 @@ func (a *App) traceRadioData(rd RadioData) {
 	}
 
 	var encoder func(data.DataPoint) string
-	switch a.outputFormat {
+	switch data.FileFormat("." + a.outputFormat) {
 	case "csv":
 		encoder = data.DataPointToCSV
 	case "json":
 		encoder = data.DataPointToJSON
 	default:
 		a.showMessage("unknown output format: %s", a.outputFormat)
+		return
 	}
 
 	for _, dataPoint := range rd.Measurement.DataPoints {
@@ -147,18 +206,68 @@ func (a *App) traceRadioData(rd RadioData) {
 
 }
 
+func (a *App) traceHandovers(events []handover.Event) {
+	for _, event := range events {
+		if event.Device != "" {
+			a.showMessage("%s: %s", event.Device, event)
+		} else {
+			a.showMessage("%s", event)
+		}
+
+		if a.handoverFile == nil {
+			continue
+		}
+		_, err := fmt.Fprintln(a.handoverFile, handover.EventToJSON(event))
+		if err != nil {
+			a.showMessage("error writing handover: %v", err)
+			return
+		}
+	}
+}
+
+func (a *App) traceOutage(outage deviceOutage) {
+	if a.traceFile == nil {
+		return
+	}
+
+	_, err := fmt.Fprintln(a.traceFile, data.OutageComment(outage.device, outage.Start, outage.End))
+	if err != nil {
+		a.showMessage("error writing outage: %v", err)
+	}
+}
+
 func (a *App) startTrace() error {
 	if a.traceFile != nil {
 		return nil
 	}
 
 	filename := a.newTraceFilename()
-	file, err := os.Create(filename)
+	file, err := data.CreateFile(filename)
 	if err != nil {
 		return fmt.Errorf("cannot create trace file: %w", err)
 	}
 	a.traceFile = file
 
+	metadata := a.metadata
+	metadata.Started = time.Now().UTC()
+	switch data.FileFormat(filename) {
+	case "csv":
+		err = data.WriteCSVHeader(file, metadata)
+	case "json":
+		err = data.WriteJSONHeader(file, metadata)
+	}
+	if err != nil {
+		a.showMessage("cannot write the trace header: %v", err)
+	}
+
+	handoverFilename := handover.Filename(filename)
+	handoverFile, err := os.Create(handoverFilename)
+	if err != nil {
+		a.showMessage("cannot create handover file: %v", err)
+	} else {
+		a.handoverFile = handoverFile
+	}
+
 	a.showMessage("tracing started")
 	a.sendStatus(filename, true)
 
@@ -175,6 +284,11 @@ func (a *App) stopTrace() error {
 		return nil
 	}
 
+	if a.handoverFile != nil {
+		a.handoverFile.Close()
+		a.handoverFile = nil
+	}
+
 	err := a.traceFile.Close()
 	a.traceFile = nil
 
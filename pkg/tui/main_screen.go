@@ -27,22 +27,18 @@ type MainScreen struct {
 	app *App
 
 	// UI state
-	version        string
-	utmField       string
-	latitude       float64
-	longitude      float64
-	satellites     int
-	lastScan       string
-	currentLAC     uint32
-	currentRSSI    int
-	currentCx      int
-	currentGAN     int
-	currentSLD     int
-	currentServers int
-	averageCount   int
-	averageRSSI    int
-	averageGAN     int
-	averageSLD     int
+	version      string
+	utmField     string
+	latitude     float64
+	longitude    float64
+	satellites   int
+	lastScan     string
+	devices      []string
+	current      map[string]currentBS
+	averageCount int
+	averageRSSI  int
+	averageGAN   int
+	averageSLD   int
 
 	// status bar content
 	userMessage   string
@@ -58,16 +54,32 @@ type MainScreen struct {
 	lacTable table.Model
 
 	// data
+	profile         data.GANProfile
 	currentPosition data.Position
 	qualityReport   *quality.QualityReport
 }
 
-func NewMainScreen(version, device string) MainScreen {
+type currentBS struct {
+	lac     uint32
+	rssi    int
+	cx      int
+	gan     int
+	sld     int
+	servers int
+}
+
+// NewMainScreen creates the main screen for the given devices. The first device is the primary device, its data
+// is used for the position, the averages, and the LAC table. The current BS is shown for each device. The GAN
+// levels are computed with the given GAN profile.
+func NewMainScreen(version, statusText string, devices []string, profile data.GANProfile) MainScreen {
 	return MainScreen{
 		version:         version,
-		device:          device,
+		device:          statusText,
+		devices:         devices,
+		current:         make(map[string]currentBS),
+		profile:         profile,
 		currentPosition: data.NoPosition,
-		qualityReport:   quality.NewQualityReport(),
+		qualityReport:   quality.NewQualityReport(profile),
 
 		keyMap: DefaultKeyMap,
 		help:   help.New(),
@@ -130,6 +142,19 @@ func (s MainScreen) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 }
 
 func (s MainScreen) handleRadioData(msg RadioData) (tea.Model, tea.Cmd) {
+	bestServer := msg.Measurement.BestServer()
+	s.current[msg.Device] = currentBS{
+		lac:     bestServer.LAC,
+		rssi:    bestServer.RSSI,
+		cx:      bestServer.Cx,
+		gan:     s.profile.RSSIToGAN(bestServer.RSSI),
+		sld:     msg.Measurement.SignalLevelDifference(),
+		servers: msg.Measurement.UsableServers(s.profile),
+	}
+	if len(s.devices) > 0 && msg.Device != s.devices[0] {
+		return s, nil
+	}
+
 	s.currentPosition = msg.Position
 	s.qualityReport.AddMeasurement(msg.Measurement)
 
@@ -140,13 +165,6 @@ func (s MainScreen) handleRadioData(msg RadioData) (tea.Model, tea.Cmd) {
 	s.lastScan = s.currentPosition.Timestamp.Local().Format("02.01.2006 15:04:05")
 
 	fieldReport := s.qualityReport.FieldReportByUTM(s.currentPosition.ToUTMField())
-	bestServer := msg.Measurement.BestServer()
-	s.currentLAC = bestServer.LAC
-	s.currentRSSI = bestServer.RSSI
-	s.currentCx = bestServer.Cx
-	s.currentGAN = data.RSSIToGAN(bestServer.RSSI)
-	s.currentSLD = msg.Measurement.SignalLevelDifference()
-	s.currentServers = msg.Measurement.UsableServers()
 	s.averageCount = len(fieldReport.Measurements)
 	s.averageRSSI = fieldReport.AverageRSSI()
 	s.averageGAN = fieldReport.AverageGAN()
@@ -189,20 +207,15 @@ func (s MainScreen) View() string {
 	if s.currentPosition.Satellites == 0 {
 		positionStyle = positionStyle.Foreground(ANSIRed).Reverse(true)
 	}
-	ganStyle := lipgloss.NewStyle().Foreground(ganToANSIColor(s.currentGAN)).Reverse(true)
-	sldStyle := lipgloss.NewStyle().Foreground(sldToANSIColor(s.currentSLD)).Reverse(true)
-	serverStyle := lipgloss.NewStyle().Foreground(serversToANSIColor(s.currentServers)).Reverse(true)
 
-	currentBox := lipgloss.JoinVertical(
-		lipgloss.Left,
-		headingStyle.Render("Current BS"),
-		fmt.Sprintf("LAC: % 7d", s.currentLAC),
-		ganStyle.Render(fmt.Sprintf("RSSI: % 6d", s.currentRSSI)),
-		ganStyle.Render(fmt.Sprintf("GAN: % 7d", s.currentGAN)),
-		fmt.Sprintf("Cx: % 8d", s.currentCx),
-		sldStyle.Render(fmt.Sprintf("SLD: % 7d", s.currentSLD)),
-		serverStyle.Render(fmt.Sprintf("Servers: %3d", s.currentServers)),
-	)
+	currentBoxes := make([]string, 0, len(s.devices))
+	for _, device := range s.devices {
+		heading := "Current BS"
+		if len(s.devices) > 1 {
+			heading = device
+		}
+		currentBoxes = append(currentBoxes, boxStyle.Width(14).Render(s.currentBox(heading, s.current[device])))
+	}
 
 	averageBox := lipgloss.JoinVertical(
 		lipgloss.Left,
@@ -221,7 +234,9 @@ func (s MainScreen) View() string {
 		lipgloss.Top,
 		statusCell.Width(2*cellWidth).Render(s.device),
 		" | ",
-		statusCell.Width(4*cellWidth).Render(s.traceFilename),
+		statusCell.Width(cellWidth).Render("GAN: "+s.profile.Name),
+		" | ",
+		statusCell.Width(3*cellWidth).Render(s.traceFilename),
 		" | ",
 		statusCell.Width(4*cellWidth).Render(s.userMessage),
 	)
@@ -235,8 +250,7 @@ func (s MainScreen) View() string {
 				positionStyle.Width(30).Render(positionBox),
 				lipgloss.JoinHorizontal(
 					lipgloss.Top,
-					boxStyle.Width(14).Render(currentBox),
-					boxStyle.Width(14).Render(averageBox),
+					append(currentBoxes, boxStyle.Width(14).Render(averageBox))...,
 				),
 			),
 			boxStyle.Width(38).Render(
@@ -250,3 +264,20 @@ func (s MainScreen) View() string {
 	screenStyle := lipgloss.NewStyle().MaxWidth(s.width).MaxHeight(s.height)
 	return screenStyle.Render(mainScreen)
 }
+
+func (s MainScreen) currentBox(heading string, current currentBS) string {
+	ganStyle := lipgloss.NewStyle().Foreground(ganToANSIColor(current.gan)).Reverse(true)
+	sldStyle := lipgloss.NewStyle().Foreground(sldToANSIColor(current.sld)).Reverse(true)
+	serverStyle := lipgloss.NewStyle().Foreground(serversToANSIColor(current.servers)).Reverse(true)
+
+	return lipgloss.JoinVertical(
+		lipgloss.Left,
+		headingStyle.Render(heading),
+		fmt.Sprintf("LAC: % 7d", current.lac),
+		ganStyle.Render(fmt.Sprintf("RSSI: % 6d", current.rssi)),
+		ganStyle.Render(fmt.Sprintf("GAN: % 7d", current.gan)),
+		fmt.Sprintf("Cx: % 8d", current.cx),
+		sldStyle.Render(fmt.Sprintf("SLD: % 7d", current.sld)),
+		serverStyle.Render(fmt.Sprintf("Servers: %3d", current.servers)),
+	)
+}